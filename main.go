@@ -1,17 +1,16 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"os"
 	"runtime/pprof"
-	"slices"
 	"strconv"
-	"strings"
-	"unicode/utf8"
+	"time"
 
 	"github.com/ross96D/battle-log-parser/parser"
 	"github.com/ross96D/battle-log-parser/server"
+	"github.com/ross96D/battle-log-parser/stats"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -19,23 +18,49 @@ import (
 
 var path string
 var pprofPath string
+var lenient bool
+var strict bool
+var reference string
+var timezone string
 
 var port uint16
+var maxUploadSize int64
+var fetchPolicy server.FetchPolicy
+var factionsPath string
 
 func init() {
+	rootCommand.PersistentFlags().StringVar(&factionsPath, "factions", "", "json file with the factions to add to the default ones")
 	rootCommand.AddCommand(&cliCommand)
 	rootCommand.AddCommand(&serveCommand)
 
-	cliCommand.Flags().StringVarP(&path, "input", "i", "battle_log.log", "the html file of the battle log to read")
+	cliCommand.Flags().StringVarP(&path, "input", "i", "battle_log.log", "the battle log to read, as html, plain text or telegram chat export")
 	cliCommand.Flags().StringVar(&pprofPath, "pprof", "", "pprof file")
+	cliCommand.Flags().BoolVar(&lenient, "lenient", false, "skip the turns that can not be parsed")
+	cliCommand.Flags().BoolVar(&strict, "strict", false, "fail on turns whose strikes do not match the declared ones")
+	cliCommand.Flags().StringVar(&reference, "reference", "", "date (YYYY-MM-DD) after the battle used to infer the year, defaults to the file modification time")
+	cliCommand.Flags().StringVar(&timezone, "timezone", "", "time zone of the dates on the log, defaults to UTC+2")
 
 	serveCommand.Flags().Uint16VarP(&port, "port", "p", 0, "set the port to listen on")
+	serveCommand.Flags().Int64Var(&maxUploadSize, "max-upload-size", 10<<20, "max size in bytes of the logs uploaded with POST")
+	serveCommand.Flags().StringSliceVar(&fetchPolicy.AllowedSchemes, "allow-scheme", []string{"http", "https"}, "url schemes allowed on /parse?url=")
+	serveCommand.Flags().StringSliceVar(&fetchPolicy.AllowedHosts, "allow-host", nil, "hosts, and their subdomains, allowed on /parse?url=, defaults to any host")
+	serveCommand.Flags().BoolVar(&fetchPolicy.AllowPrivateAddresses, "allow-private", false, "allow urls resolving to loopback and private addresses")
+	serveCommand.Flags().DurationVar(&fetchPolicy.Timeout, "fetch-timeout", 10*time.Second, "timeout of the requests for the url param")
+	serveCommand.Flags().Int64Var(&fetchPolicy.MaxBodySize, "max-fetch-size", 10<<20, "max size in bytes of the log downloaded for the url param")
+	serveCommand.Flags().IntVar(&fetchPolicy.MaxRedirects, "max-redirects", 3, "max redirects followed for the url param, negative disables them")
 	if err := serveCommand.MarkFlagRequired("port"); err != nil {
 		panic(err)
 	}
 }
 
-var rootCommand = cobra.Command{}
+var rootCommand = cobra.Command{
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if factionsPath != "" {
+			return parser.LoadFactionsFile(factionsPath)
+		}
+		return nil
+	},
+}
 
 var cliCommand = cobra.Command{
 	Use: "cli",
@@ -58,20 +83,50 @@ var cliCommand = cobra.Command{
 		}
 		defer f.Close()
 
-		battle, err := parser.Parse(f)
-		if err != nil {
+		opts := parser.Options{Lenient: lenient, Strict: strict}
+		if reference != "" {
+			opts.Reference, err = time.Parse(time.DateOnly, reference)
+			if err != nil {
+				panic(err)
+			}
+			// take the whole day
+			opts.Reference = opts.Reference.AddDate(0, 0, 1)
+		} else if info, err := f.Stat(); err == nil {
+			opts.Reference = info.ModTime()
+		}
+		if timezone != "" {
+			opts.Location, err = time.LoadLocation(timezone)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		battles, err := parser.ParseAuto(f, opts)
+		if errors.Is(err, parser.ErrTruncatedLog) {
+			println("warning:", err.Error())
+		} else if err != nil {
 			panic(err)
 		}
 
-		m := PlayerResumen(battle)
-		// TODO print on the stdout instead of the stderr
-		println("Battle resume by player")
-		for _, k := range sort(m) {
-			p := m[k]
-			// println(k.Team.String(), "\t"+k.Name, "\tdone:", p.Damage, "\trecieved:", p.Tanqued, "\thits/total", fmt.Sprintf("%d/%d", p.Hits, p.Hits+p.Miss), fmt.Sprintf("%f%%", float64(p.Hits)/float64(p.Miss+p.Hits)), "\tCrits:", p.Crits)
-			println(p.String())
+		for _, battle := range battles {
+			for _, w := range battle.Warnings {
+				println("warning:", w.Reason)
+			}
+
+			summary := stats.Summarize(battle)
+			// TODO print on the stdout instead of the stderr
+			println("Battle resume by player")
+			for _, p := range summary.Players {
+				println(p.String())
+			}
+			if len(summary.Guilds) > 0 {
+				println("Battle resume by guild")
+				for _, g := range summary.Guilds {
+					println(g.String())
+				}
+			}
+			println(battle.Date.String(), battle.Date.UnixMilli())
 		}
-		println(battle.Date.String(), battle.Date.UnixMilli())
 	},
 }
 
@@ -79,7 +134,10 @@ var serveCommand = cobra.Command{
 	Use: "serve",
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Debug().Msgf("Serving on %d", port)
-		if err := http.ListenAndServe(":"+strconv.FormatUint(uint64(port), 10), server.Server()); err != nil {
+		if err := http.ListenAndServe(":"+strconv.FormatUint(uint64(port), 10), server.Server(server.Config{
+			MaxUploadSize: maxUploadSize,
+			Fetch:         fetchPolicy,
+		})); err != nil {
 			log.Panic().Err(err).Send()
 		}
 	},
@@ -93,117 +151,3 @@ func main() {
 		os.Exit(1)
 	}
 }
-
-func sort(m map[parser.User]PlayerResume) []parser.User {
-	type Z struct {
-		k parser.User
-		v PlayerResume
-	}
-	result := make([]Z, 0)
-	for k, v := range m {
-		result = append(result, Z{k: k, v: v})
-	}
-	slices.SortFunc(result, func(a Z, b Z) int {
-		return b.v.Damage - a.v.Damage
-	})
-	ss := make([]parser.User, 0, len(result))
-	for _, v := range result {
-		ss = append(ss, v.k)
-	}
-	return ss
-}
-
-type PlayerResume struct {
-	Team    parser.Team
-	Damage  int
-	Tanqued int
-	Miss    int
-	Hits    int
-	Crits   int
-	Name    string
-}
-
-func (pr PlayerResume) String() string {
-	b := strings.Builder{}
-	b.WriteString(pr.Team.String())
-	b.WriteString(fmt.Sprintf(
-		" %s\tdmg: %s\trecieved: %d\tHits/Total: %d/%d %.1f%%\tcrits: %d",
-		pr.NameWithFixedWidth(13), FixedLenStr(strconv.FormatInt(int64(pr.Damage), 10), 5), pr.Tanqued, pr.Hits, pr.Hits+pr.Miss, 100*float64(pr.Hits)/float64(pr.Hits+pr.Miss), pr.Crits),
-	)
-	return b.String()
-}
-
-func (pr PlayerResume) NameWithFixedWidth(width uint) string {
-	return FixedLenStr(pr.Name, width)
-}
-
-func (pr PlayerResume) Add(other PlayerResume) PlayerResume {
-	return PlayerResume{
-		Team:    pr.Team,
-		Name:    pr.Name,
-		Damage:  pr.Damage + other.Damage,
-		Tanqued: pr.Tanqued + other.Tanqued,
-		Hits:    pr.Hits + other.Hits,
-		Miss:    pr.Miss + other.Miss,
-		Crits:   pr.Crits + other.Crits,
-	}
-}
-
-func PlayerResumen(b parser.Battle) map[parser.User]PlayerResume {
-	empty := PlayerResume{}
-
-	result := make(map[parser.User]PlayerResume, 0)
-	for _, turn := range b.Turns {
-		r := result[turn.Attacker]
-		new := PlayerResume{
-			Damage: turn.Damage(),
-			Miss:   turn.Misses(),
-			Hits:   turn.Hits(),
-			Crits:  turn.Crits(),
-		}
-		if r == empty {
-			r.Name = turn.Attacker.Name
-			r.Team = turn.Attacker.Team
-		}
-
-		result[turn.Attacker] = r.Add(new)
-
-		if !turn.Target.IsMiss() {
-			r = result[turn.Target]
-			if r == empty {
-				r.Name = turn.Target.Name
-				r.Team = turn.Target.Team
-			}
-			result[turn.Target] = r.Add(PlayerResume{Tanqued: turn.Damage(), Team: turn.Target.Team})
-		}
-	}
-	return result
-}
-
-func FixedLenStr(str string, width uint) string {
-	strB := []byte(str)
-	nameLen := utf8.RuneCount(strB)
-	if nameLen > int(width) {
-		result := make([]byte, 0, width)
-		for i, r := range str {
-			if i == int(width) {
-				break
-			}
-			if r < 128 {
-				result = utf8.AppendRune(result, r)
-			} else {
-				_, size := utf8.DecodeRuneInString(string(r))
-				if len(result)+size > int(width) {
-					break
-				}
-				result = utf8.AppendRune(result, r)
-			}
-		}
-		return string(result)
-	} else {
-		for i := uint(0); i < (width - uint(nameLen)); i++ {
-			strB = append(strB, ' ')
-		}
-		return string(strB)
-	}
-}
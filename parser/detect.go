@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+type Format int
+
+const (
+	FormatHTML Format = iota
+	FormatText
+	FormatTelegramJSON
+	FormatTelegramHTML
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatHTML:
+		return "html"
+	case FormatText:
+		return "text"
+	case FormatTelegramJSON:
+		return "telegram-json"
+	case FormatTelegramHTML:
+		return "telegram-html"
+	default:
+		return "unknown"
+	}
+}
+
+func DetectFormat(data []byte) Format {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(data) == 0 {
+		return FormatText
+	}
+	switch data[0] {
+	case '{':
+		return FormatTelegramJSON
+	case '<':
+		if bytes.Contains(data, []byte(`class="message default`)) {
+			return FormatTelegramHTML
+		}
+		return FormatHTML
+	default:
+		return FormatText
+	}
+}
+
+// ParseAuto detects the format of the input and parses it. Chat exports may
+// return many battles. HTML pages and plain text return a one element slice;
+// when the log was cut that slice holds the partial battle and the error is
+// ErrTruncatedLog.
+func ParseAuto(r io.Reader, opts Options) ([]Battle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Battle
+	switch DetectFormat(data) {
+	case FormatTelegramJSON:
+		return ParseTelegramExport(bytes.NewReader(data), opts)
+	case FormatTelegramHTML:
+		return ParseTelegramHTMLExport(bytes.NewReader(data), opts)
+	case FormatHTML:
+		b, err = ParseWithOptions(io.NopCloser(bytes.NewReader(data)), opts)
+	default:
+		b, err = ParseText(bytes.NewReader(data), opts)
+	}
+	if err != nil && !errors.Is(err, ErrTruncatedLog) {
+		return nil, err
+	}
+	return []Battle{b}, err
+}
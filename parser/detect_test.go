@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		file   string
+		format Format
+	}{
+		{file: "territory.html", format: FormatHTML},
+		{file: "no_resume.html", format: FormatHTML},
+		{file: "pasted.txt", format: FormatText},
+		{file: "telegram.json", format: FormatTelegramJSON},
+		{file: "telegram_export.html", format: FormatTelegramHTML},
+	}
+	for _, test := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata/logs", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format := DetectFormat(data); format != test.format {
+			t.Errorf("%s expected %s got %s", test.file, test.format, format)
+		}
+	}
+
+	if format := DetectFormat([]byte("\xef\xbb\xbf \n<html>")); format != FormatHTML {
+		t.Errorf("expected html after the BOM got %s", format)
+	}
+	if format := DetectFormat(nil); format != FormatText {
+		t.Errorf("expected text for empty input got %s", format)
+	}
+}
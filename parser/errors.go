@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrUnsupportedLayout Error = "unsupported layout"
+	ErrNoBattleLog       Error = "no battle log found"
+	ErrTruncatedLog      Error = "battle log is truncated"
+)
+
+type Category int
+
+const (
+	CategoryResume Category = iota
+	CategoryIdentifier
+	CategoryTurn
+	CategoryStrike
+)
+
+func (c Category) String() string {
+	switch c {
+	case CategoryResume:
+		return "resume"
+	case CategoryIdentifier:
+		return "identifier"
+	case CategoryTurn:
+		return "turn"
+	case CategoryStrike:
+		return "strike"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseError reports a line of the battle log that could not be parsed.
+type ParseError struct {
+	Category Category
+	// index of the card inside the document
+	Card int
+	// index of the line inside the card
+	Line int
+	Text string
+	Err  error
+}
+
+func newParseError(category Category, line int, text string, err error) *ParseError {
+	return &ParseError{
+		Category: category,
+		Line:     line,
+		Text:     text,
+		Err:      err,
+	}
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: card %d line %d %q: %s", e.Category, e.Card, e.Line, e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// offsetCard moves the card index of a *ParseError by offset, so callers
+// that only see a slice of the cards can report the index on the document.
+func offsetCard(err error, offset int) error {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Card += offset
+	}
+	return err
+}
+
+// offsetLine moves the line index of a *ParseError by offset.
+func offsetLine(err error, offset int) error {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Line += offset
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Faction describes a team of the game, the castles and the monsters.
+type Faction struct {
+	// letter used on positions and resume teams
+	Code Team
+	Name string
+	// flag of the faction on the resume and on the JSON of the team
+	Flag string
+	// flag written before the players on the turns, defaults to Flag
+	TurnFlag string
+	// other flags read as the faction
+	Aliases []string
+	// start of the faction line on the battle resume
+	ResumePrefix string
+	// the faction are the creatures of monster battles, not a castle
+	Monster bool
+}
+
+// factionConfig is the Faction on the configuration file
+type factionConfig struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Flag         string   `json:"flag"`
+	TurnFlag     string   `json:"turn_flag"`
+	Aliases      []string `json:"aliases"`
+	ResumePrefix string   `json:"resume_prefix"`
+	Monster      bool     `json:"monster"`
+}
+
+var defaultFactions = []Faction{
+	{Code: 'G', Name: "Green", Flag: "🇲🇴", ResumePrefix: "🇲🇴Green Castle"},
+	{Code: 'Y', Name: "Yellow", Flag: "🇻🇦", ResumePrefix: "🇻🇦Yellow Castle"},
+	{Code: 'B', Name: "Blue", Flag: "🇪🇺", ResumePrefix: "🇪🇺Blue Castle"},
+	{Code: 'R', Name: "Red", Flag: "🇮🇲", ResumePrefix: "🇮🇲Red Castle"},
+	{Code: 'M', Name: "Monster", Flag: "👹", TurnFlag: "⚱️", ResumePrefix: "👹Creatures", Monster: true},
+}
+
+var (
+	factionsMu sync.RWMutex
+	factions   = defaultFactions
+)
+
+// Factions returns the registered factions
+func Factions() []Faction {
+	factionsMu.RLock()
+	defer factionsMu.RUnlock()
+	return append([]Faction(nil), factions...)
+}
+
+// RegisterFaction adds the faction or replaces the one with the same code
+func RegisterFaction(f Faction) error {
+	if f.Code == 0 {
+		return errors.New("faction without code")
+	}
+	if f.Flag == "" {
+		return fmt.Errorf("faction %c without flag", f.Code)
+	}
+
+	factionsMu.Lock()
+	defer factionsMu.Unlock()
+	updated := make([]Faction, 0, len(factions)+1)
+	for _, faction := range factions {
+		if faction.Code != f.Code {
+			updated = append(updated, faction)
+		}
+	}
+	factions = append(updated, f)
+	return nil
+}
+
+// LoadFactions registers the factions of a JSON list as
+// [{"code": "W", "name": "White", "flag": "🏳", "resume_prefix": "🏳White Castle"}]
+// with "turn_flag" when the turns write another flag and "monster": true for
+// the factions of creatures
+func LoadFactions(r io.Reader) error {
+	var configs []factionConfig
+	if err := json.NewDecoder(r).Decode(&configs); err != nil {
+		return fmt.Errorf("factions %w", err)
+	}
+	for _, config := range configs {
+		if len(config.Code) != 1 {
+			return fmt.Errorf("faction code must be one letter, found %q", config.Code)
+		}
+		err := RegisterFaction(Faction{
+			Code:         Team(config.Code[0]),
+			Name:         config.Name,
+			Flag:         config.Flag,
+			TurnFlag:     config.TurnFlag,
+			Aliases:      config.Aliases,
+			ResumePrefix: config.ResumePrefix,
+			Monster:      config.Monster,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFactionsFile registers the factions of a JSON file, see LoadFactions
+func LoadFactionsFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return LoadFactions(f)
+}
+
+func FactionByCode(code Team) (Faction, bool) {
+	factionsMu.RLock()
+	defer factionsMu.RUnlock()
+	for _, faction := range factions {
+		if faction.Code == code {
+			return faction, true
+		}
+	}
+	return Faction{}, false
+}
+
+// hasFlag reports if the flag grapheme is the flag, turn flag or an alias
+// of the faction
+func (f Faction) hasFlag(flag string) bool {
+	if sameGrapheme(flag, f.Flag) {
+		return true
+	}
+	if f.TurnFlag != "" && sameGrapheme(flag, f.TurnFlag) {
+		return true
+	}
+	for _, alias := range f.Aliases {
+		if sameGrapheme(flag, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// turnFlag is the flag written before the players on the turns
+func (f Faction) turnFlag() string {
+	if f.TurnFlag != "" {
+		return f.TurnFlag
+	}
+	return f.Flag
+}
+
+// factionByFlag finds the faction of a flag grapheme
+func factionByFlag(flag string) (Faction, bool) {
+	factionsMu.RLock()
+	defer factionsMu.RUnlock()
+	for _, faction := range factions {
+		if faction.hasFlag(flag) {
+			return faction, true
+		}
+	}
+	return Faction{}, false
+}
+
+// factionByName finds the faction by name or by any of its flags
+func factionByName(s string) (Faction, bool) {
+	factionsMu.RLock()
+	defer factionsMu.RUnlock()
+	for _, faction := range factions {
+		if strings.EqualFold(s, faction.Name) || faction.hasFlag(s) {
+			return faction, true
+		}
+	}
+	return Faction{}, false
+}
+
+// factionByResumeLine finds the faction whose resume prefix starts the line,
+// returning the rest of the line
+func factionByResumeLine(line string) (Faction, string, bool) {
+	factionsMu.RLock()
+	defer factionsMu.RUnlock()
+	for _, faction := range factions {
+		if faction.ResumePrefix == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, faction.ResumePrefix); ok {
+			return faction, rest, true
+		}
+	}
+	return Faction{}, "", false
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadFactions(t *testing.T) {
+	t.Cleanup(func() {
+		factions = defaultFactions
+	})
+
+	config := `[
+		{"code": "W", "name": "White", "flag": "🏳️", "resume_prefix": "🏳️White Castle"},
+		{"code": "D", "name": "Dragon", "flag": "🐉", "monster": true}
+	]`
+	if err := LoadFactions(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := UserFromString("🏳️Wendy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Team != 'W' || u.Team.Name() != "White" || u.Name != "Wendy" {
+		t.Errorf("unexpected user %+v", u)
+	}
+
+	teams, err := ParseResumeTeams([]string{"🏳️White Castle: 3 total, 1 alive"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 1 || teams[0].Team != 'W' || teams[0].Total != 3 || teams[0].Alive != 1 {
+		t.Errorf("unexpected teams %+v", teams)
+	}
+
+	var team Team
+	if err := team.UnmarshalJSON([]byte(`"White"`)); err != nil || team != 'W' {
+		t.Errorf("unmarshal White %c %v", team, err)
+	}
+
+	if faction, ok := FactionByCode('D'); !ok || !faction.Monster {
+		t.Errorf("expected monster faction D got %+v", faction)
+	}
+	if faction, _ := FactionByCode('W'); faction.Monster {
+		t.Errorf("expected castle faction W got %+v", faction)
+	}
+	if _, err := ParsePosition("DG"); err == nil {
+		t.Errorf("expected error for monster position DG")
+	}
+}
+
+func TestUnknownTeam(t *testing.T) {
+	team := Team('Z')
+	if _, err := team.MarshalJSON(); !errors.Is(err, ErrUnknownTeam) {
+		t.Errorf("expected ErrUnknownTeam got %v", err)
+	}
+	if err := team.UnmarshalJSON([]byte(`"Purple"`)); !errors.Is(err, ErrUnknownTeam) {
+		t.Errorf("expected ErrUnknownTeam got %v", err)
+	}
+	if team.String() == "" || team.Name() == "" {
+		t.Errorf("unknown team without name")
+	}
+}
+
+func TestTeamJSON(t *testing.T) {
+	b, err := json.Marshal(Team('M'))
+	if err != nil || string(b) != `"👹"` {
+		t.Errorf("marshal monster %s %v", b, err)
+	}
+
+	for _, s := range []string{`"Monster"`, `"👹"`, `"⚱️"`, `"⚱"`} {
+		var team Team
+		if err := json.Unmarshal([]byte(s), &team); err != nil || team != 'M' {
+			t.Errorf("unmarshal %s %c %v", s, team, err)
+		}
+	}
+
+	u := User{Team: 'M', Name: "Forest Troll"}
+	if u.LogString() != "⚱️Forest Troll" {
+		t.Errorf("unexpected log string %s", u.LogString())
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fuzz targets only check that the parser never panics, every input
+// must return an error or a value.
+
+func FuzzParseStrikeLine(f *testing.F) {
+	for _, seed := range []string{
+		"strike! dmg: 30. Pdef was: 5",
+		"crit strike! dmg: 30. Pdef was: 5",
+		"⚡️💦crit strike! dmg: 80. Pdef was: 2",
+		"🔄Alice counter strike! dmg: 4. Pdef was: 1",
+		"🔄Carol counter miss!",
+		"miss!",
+		"dodge!",
+		"➕",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		strike, err := parseStrikeLine(line)
+		if err != nil && strike != (Strike{}) {
+			t.Errorf("strike returned with error %v", err)
+		}
+	})
+}
+
+func FuzzParsePosition(f *testing.F) {
+	for _, seed := range []string{"G3#4", "GY5", "RB", "M1#2", "G", "#", "GY-1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, position string) {
+		pos, err := ParsePosition(position)
+		if err != nil {
+			return
+		}
+		again, err := ParsePosition(pos.String())
+		if err != nil || again != pos {
+			t.Errorf("%q does not round trip: %q %v", position, pos.String(), err)
+		}
+	})
+}
+
+func FuzzUserFromString(f *testing.F) {
+	for _, seed := range []string{"🇲🇴Alice", "⚱️Forest Troll", "👹", "Alice", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := UserFromString(s)
+		if err != nil && u != (User{}) {
+			t.Errorf("user returned with error %v", err)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, dir := range []string{"testdata/logs/*.html", "testdata/broken/*"} {
+		files, err := filepath.Glob(dir)
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := Parse(io.NopCloser(bytes.NewReader(data)))
+		if err != nil {
+			return
+		}
+		if err := RenderHTML(io.Discard, b, Options{}); err != nil {
+			t.Errorf("parsed battle can not be rendered %v", err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGolden parses every log on testdata/logs and compares the battles
+// with the json file of the same name. Run with -update to rewrite them.
+//
+// The logs are not game exports yet, they are written from the lines the
+// first parser read. The lines it skipped are placeholders until anonymised
+// real logs replace them: the second line of the resume, the last card, the
+// line of a turn without target and the text between 🔄 and the strike.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/logs/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file, ".golden.json") {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			battles, err := ParseAuto(f, Options{Reference: testReference})
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := json.MarshalIndent(battles, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			golden := strings.TrimSuffix(file, filepath.Ext(file)) + ".golden.json"
+			if *update {
+				if err := os.WriteFile(golden, actual, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("battle differs from %s\n%s", golden, actual)
+			}
+		})
+	}
+}
+
+// htmlLogs returns the battle log pages on testdata/logs, leaving out the
+// chat exports
+func htmlLogs(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("testdata/logs/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if DetectFormat(data) == FormatHTML {
+			result = append(result, file)
+		}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// graphemeLen returns the length in bytes of the first grapheme cluster of
+// s. It is not a full implementation of the unicode segmentation, it covers
+// the sequences found on emoji: regional indicator pairs (flags), variation
+// selectors, skin tones, zero width joiner sequences, tag sequences, keycaps
+// and combining marks.
+func graphemeLen(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return 0
+	}
+	if isRegionalIndicator(r) {
+		if r2, size2 := utf8.DecodeRuneInString(s[size:]); isRegionalIndicator(r2) {
+			size += size2
+		}
+		return size
+	}
+
+	for size < len(s) {
+		r, n := utf8.DecodeRuneInString(s[size:])
+		switch {
+		case isGraphemeExtend(r):
+			size += n
+		case r == '\u200d':
+			// zero width joiner, the next rune is part of the cluster
+			size += n
+			if size < len(s) {
+				_, n = utf8.DecodeRuneInString(s[size:])
+				size += n
+			}
+		default:
+			return size
+		}
+	}
+	return size
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // skin tones
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags
+		return true
+	case r == 0x20E3: // keycap
+		return true
+	default:
+		return unicode.In(r, unicode.Mn, unicode.Me)
+	}
+}
+
+// sameGrapheme compares two clusters ignoring the emoji variation selector,
+// that the game does not always write.
+func sameGrapheme(a string, b string) bool {
+	return strings.TrimSuffix(a, "\ufe0f") == strings.TrimSuffix(b, "\ufe0f")
+}
+
+// isEmojiGrapheme reports whether the cluster is a pictograph or flag, used
+// to tell the decoration of a name from its text.
+func isEmojiGrapheme(g string) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return unicode.Is(unicode.So, r) || isRegionalIndicator(r)
+}
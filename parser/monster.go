@@ -0,0 +1,76 @@
+package parser
+
+// CreatureStats is what a creature did on a battle. The creature is only
+// known by the name written after ⚱️ on the turns.
+type CreatureStats struct {
+	Name        string `json:"name"`
+	DamageTaken int    `json:"damage_taken"`
+	DamageDealt int    `json:"damage_dealt"`
+	// biggest damage of a single strike of the creature
+	MaxStrike int  `json:"max_strike"`
+	Died      bool `json:"died"`
+}
+
+func isMonster(u User) bool {
+	faction, ok := FactionByCode(u.Team)
+	return ok && faction.Monster
+}
+
+// findCreatures sums the damage of each creature, counter strikes are
+// damage dealt by the target
+func findCreatures(turns []Turn) []CreatureStats {
+	result := make([]CreatureStats, 0)
+	index := make(map[string]int)
+	stats := func(u User) *CreatureStats {
+		i, ok := index[u.Name]
+		if !ok {
+			i = len(result)
+			index[u.Name] = i
+			result = append(result, CreatureStats{Name: u.Name})
+		}
+		return &result[i]
+	}
+
+	for _, turn := range turns {
+		if isMonster(turn.Attacker) {
+			s := stats(turn.Attacker)
+			s.DamageDealt += turn.Damage()
+			s.DamageTaken += turn.CounterDamage()
+			for _, strike := range turn.Strikes {
+				if !strike.Counter {
+					s.MaxStrike = max(s.MaxStrike, strike.Damage)
+				}
+			}
+		}
+		if isMonster(turn.Target) {
+			s := stats(turn.Target)
+			s.DamageTaken += turn.Damage()
+			s.DamageDealt += turn.CounterDamage()
+			for _, strike := range turn.Strikes {
+				if strike.Counter {
+					s.MaxStrike = max(s.MaxStrike, strike.Damage)
+				}
+			}
+			if turn.TargetDied {
+				s.Died = true
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// battleKind infers the kind of a battle without resume from its turns
+func battleKind(turns []Turn) BattleKind {
+	if len(turns) == 0 {
+		return BattleUnknown
+	}
+	for _, turn := range turns {
+		if isMonster(turn.Attacker) || isMonster(turn.Target) {
+			return BattleMonster
+		}
+	}
+	return BattleTerritory
+}
@@ -0,0 +1,28 @@
+package parser
+
+import "testing"
+
+func TestBattleKind(t *testing.T) {
+	t.Cleanup(func() {
+		factions = defaultFactions
+	})
+
+	castle := User{Team: 'G', Name: "Alice"}
+	if kind := battleKind(nil); kind != BattleUnknown {
+		t.Errorf("expected unknown got %s", kind)
+	}
+	if kind := battleKind([]Turn{{Attacker: castle}}); kind != BattleTerritory {
+		t.Errorf("expected territory got %s", kind)
+	}
+	if kind := battleKind([]Turn{{Attacker: castle, Target: User{Team: 'M', Name: "Wolf"}}}); kind != BattleMonster {
+		t.Errorf("expected monster got %s", kind)
+	}
+
+	err := RegisterFaction(Faction{Code: 'U', Name: "Undead", Flag: "💀", Monster: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := battleKind([]Turn{{Attacker: User{Team: 'U', Name: "Ghoul"}, Target: castle}}); kind != BattleMonster {
+		t.Errorf("expected monster for a registered monster faction got %s", kind)
+	}
+}
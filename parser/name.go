@@ -0,0 +1,57 @@
+package parser
+
+import "strings"
+
+// NameParts is a player name split on its guild tag, as in "[ABC]Name", the
+// emoji that decorate it and the nick.
+type NameParts struct {
+	Guild string   `json:"guild,omitempty"`
+	Emoji []string `json:"emoji,omitempty"`
+	Nick  string   `json:"nick"`
+}
+
+// SplitName splits the name of a player, the guild tag and the emoji are
+// taken from both ends of the name in any order.
+func SplitName(name string) NameParts {
+	parts := NameParts{}
+	name = strings.TrimSpace(name)
+
+	for name != "" {
+		if strings.HasPrefix(name, "[") {
+			if end := strings.IndexByte(name, ']'); end != -1 && parts.Guild == "" {
+				parts.Guild = name[1:end]
+				name = strings.TrimSpace(name[end+1:])
+				continue
+			}
+		}
+		size := graphemeLen(name)
+		if !isEmojiGrapheme(name[:size]) {
+			break
+		}
+		parts.Emoji = append(parts.Emoji, name[:size])
+		name = strings.TrimSpace(name[size:])
+	}
+
+	for name != "" {
+		last := lastGraphemeStart(name)
+		if !isEmojiGrapheme(name[last:]) {
+			break
+		}
+		parts.Emoji = append(parts.Emoji, name[last:])
+		name = strings.TrimSpace(name[:last])
+	}
+
+	parts.Nick = name
+	return parts
+}
+
+// lastGraphemeStart returns the byte index where the last grapheme cluster
+// of s starts.
+func lastGraphemeStart(s string) int {
+	start := 0
+	for i := 0; i < len(s); {
+		start = i
+		i += graphemeLen(s[i:])
+	}
+	return start
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected NameParts
+	}{
+		{"Alice", NameParts{Nick: "Alice"}},
+		{"[ABC]Alice", NameParts{Guild: "ABC", Nick: "Alice"}},
+		{"🔥[ABC] Alice", NameParts{Guild: "ABC", Emoji: []string{"🔥"}, Nick: "Alice"}},
+		{"[ABC]🎃Alice🎃", NameParts{Guild: "ABC", Emoji: []string{"🎃", "🎃"}, Nick: "Alice"}},
+		{"👨‍👩‍👧Bob", NameParts{Emoji: []string{"👨‍👩‍👧"}, Nick: "Bob"}},
+		{"Forest Troll", NameParts{Nick: "Forest Troll"}},
+		{"[Alice", NameParts{Nick: "[Alice"}},
+	}
+	for _, test := range tests {
+		if actual := SplitName(test.name); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%q expected %+v got %+v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestTeamFromRune(t *testing.T) {
+	tests := []struct {
+		s    string
+		team Team
+		rest string
+	}{
+		{"🇲🇴Alice", 'G', "Alice"},
+		{"🇻🇦🔥Alice", 'Y', "🔥Alice"},
+		{"⚱️Forest Troll", 'M', "Forest Troll"},
+		// without variation selector
+		{"⚱Forest Troll", 'M', "Forest Troll"},
+		{"👹Creatures", 'M', "Creatures"},
+	}
+	for _, test := range tests {
+		team, size, err := TeamFromRune(test.s)
+		if err != nil {
+			t.Errorf("%q %v", test.s, err)
+			continue
+		}
+		if team != test.team || test.s[size:] != test.rest {
+			t.Errorf("%q expected %c %q got %c %q", test.s, test.team, test.rest, team, test.s[size:])
+		}
+	}
+
+	for _, s := range []string{"", "Alice", "🇺🇸Alice", "🇲Alice"} {
+		if _, _, err := TeamFromRune(s); err == nil {
+			t.Errorf("%q expected error", s)
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	valid := []string{"G3#4", "GY5", "GY", "RB", "R2#7"}
+	for _, s := range valid {
+		pos, err := ParsePosition(s)
+		if err != nil {
+			t.Errorf("%s unexpected error %v", s, err)
+			continue
+		}
+		if pos.String() != s {
+			t.Errorf("%s round trip got %s", s, pos)
+		}
+	}
+
+	invalid := []string{"GG", "GG3", "GM", "GM3", "MG", "M3#4", "XY", "G", "G3"}
+	for _, s := range invalid {
+		if _, err := ParsePosition(s); err == nil {
+			t.Errorf("%s expected error", s)
+		}
+	}
+}
+
+func TestUserKey(t *testing.T) {
+	abc, err := UserFromString("🇲🇴[ABC]Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xyz, err := UserFromString("🇲🇴[XYZ]Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decorated, err := UserFromString("🇲🇴[ABC]🔥Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abc.Key() == xyz.Key() {
+		t.Errorf("expected different keys for different guilds")
+	}
+	if abc.Key() != decorated.Key() {
+		t.Errorf("expected the same key ignoring the decoration got %+v %+v", abc.Key(), decorated.Key())
+	}
+}
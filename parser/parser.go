@@ -2,77 +2,185 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ross96D/battle-log-parser/assert"
 	"golang.org/x/net/html"
 )
 
-func Parse(data io.ReadCloser) (b Battle, err error) {
+type Options struct {
+	// Lenient skips the turns that can not be parsed and reports them
+	// on Battle.Warnings instead of failing the whole battle
+	Lenient bool
+	// Strict fails on turns that parse but do not match what the log
+	// declares, as the number of strikes on the target line. Otherwise the
+	// mismatch is only recorded on the turn, see Turn.StrikesMismatch
+	Strict bool
+	// Reference is a time close after the battle (HTTP Last-Modified, file
+	// modification time...) used to infer the year of the battle date, that
+	// the log does not carry. Defaults to time.Now()
+	Reference time.Time
+	// Location is the time zone of the dates written on the log. Defaults to UTC+2
+	Location *time.Location
+}
+
+var defaultLocation = time.FixedZone("UTC+2", 2*60*60)
+
+func (opts Options) withDefaults() Options {
+	if opts.Reference.IsZero() {
+		opts.Reference = time.Now()
+	}
+	if opts.Location == nil {
+		opts.Location = defaultLocation
+	}
+	return opts
+}
+
+// Parse reads the battle log page. Pages without a battle log return
+// ErrNoBattleLog. When the log was cut the battle read up to the last
+// complete card is returned together with ErrTruncatedLog.
+func Parse(data io.ReadCloser) (Battle, error) {
+	return ParseWithOptions(data, Options{})
+}
+
+func ParseWithOptions(data io.ReadCloser, opts Options) (Battle, error) {
 	root, err := html.Parse(data)
 	if err != nil {
-		return
+		return Battle{}, err
 	}
 	data.Close()
 
+	b, err := parseDocument(root, opts.withDefaults())
+	if err != nil && !errors.Is(err, ErrTruncatedLog) {
+		return Battle{}, err
+	}
+	completeBattle(&b)
+	return b, err
+}
+
+// completeBattle fills what is computed from the turns
+func completeBattle(b *Battle) {
+	b.Deaths = findDeaths(b.Turns)
+	b.Creatures = findCreatures(b.Turns)
+	if b.Resume.Kind == BattleUnknown {
+		b.Resume.Kind = battleKind(b.Turns)
+	}
+}
+
+func parseDocument(root *html.Node, opts Options) (Battle, error) {
 	body := find(root, func(n *html.Node) bool {
 		return n.Data == "body"
 	})
 
-	cardList := findAll(body, func(n *html.Node) bool {
-		if value, ok := attr(n, "class"); ok {
-			return value == "card"
+	source := detectSource(body)
+	if source == nil {
+		if body == nil || len(findCards(body)) == 0 {
+			return Battle{}, ErrNoBattleLog
 		}
-		return false
-	}, nil)
+		return Battle{}, ErrUnsupportedLayout
+	}
+	return source.Parse(body, opts)
+}
 
-	// missing resume and no card instead p
-	if len(cardList) == 2 {
-		return parseMissinResumeBattleLog(cardList[0])
+func nodesLines(nodes []*html.Node) [][]string {
+	result := make([][]string, 0, len(nodes))
+	for _, n := range nodes {
+		result = append(result, getNodeLines(n))
 	}
+	return result
+}
 
-	resumeNode := cardList[0]
-	identifierNode := cardList[1]
-	_ = identifierNode
+// parseCards parses the lines of each card on the card layout: resume,
+// identifier, the turns and a last card that is not a turn.
+func parseCards(cards [][]string, opts Options) (b Battle, err error) {
+	if len(cards) == 0 {
+		return Battle{}, ErrNoBattleLog
+	}
 
-	b.Date, err = ParseIdentifierNode(identifierNode)
+	b.Resume, err = ParseResumeLines(cards[0])
 	if err != nil {
 		return Battle{}, err
 	}
+	if len(cards) < 2 {
+		b.Turns = []Turn{}
+		return b, ErrTruncatedLog
+	}
 
-	b.Resume = ParseResumeNode(resumeNode)
-	b.Turns = ParseTurnNodes(cardList[2 : len(cardList)-1])
+	b.ID, b.Date, err = ParseIdentifierLines(cards[1], opts)
+	if err != nil {
+		return Battle{}, offsetCard(err, 1)
+	}
 
-	return
+	var truncated bool
+	b.Turns, b.Warnings, truncated, err = parseLogTurns(cards[2:], 2, opts)
+	if err != nil {
+		return Battle{}, err
+	}
+	if truncated {
+		return b, ErrTruncatedLog
+	}
+	return b, nil
 }
 
-func parseMissinResumeBattleLog(n *html.Node) (b Battle, err error) {
-	pList := findAll(n, func(n *html.Node) bool {
-		return n.Data == "p"
-	}, nil)
+// parseResumelessCards parses the lines of each card on the layout without
+// resume: identifier, the turns and a last card that is not a turn.
+func parseResumelessCards(cards [][]string, opts Options) (b Battle, err error) {
+	if len(cards) == 0 {
+		return Battle{}, ErrNoBattleLog
+	}
 
-	identifierNode := pList[0]
-	b.Date, err = ParseIdentifierNode(identifierNode)
+	b.ID, b.Date, err = ParseIdentifierLines(cards[0], opts)
 	if err != nil {
 		return Battle{}, err
 	}
 
-	b.Turns = ParseTurnNodes(pList[1 : len(pList)-1])
-	return
+	var truncated bool
+	b.Turns, b.Warnings, truncated, err = parseLogTurns(cards[1:], 1, opts)
+	if err != nil {
+		return Battle{}, err
+	}
+	if truncated {
+		return b, ErrTruncatedLog
+	}
+	return b, nil
+}
+
+// ParseIdentifierNode returns the text before the date of the identifier
+// card, that identifies the battle, and the battle date.
+func ParseIdentifierNode(n *html.Node, opts Options) (id string, date time.Time, err error) {
+	return ParseIdentifierLines(getNodeLines(n), opts)
 }
 
-func ParseIdentifierNode(n *html.Node) (time.Time, error) {
-	lines := getNodeLines(n)
-	assert.Assert(len(lines) == 1, "IdentifierNode have only one line %d", len(lines))
+func ParseIdentifierLines(lines []string, opts Options) (id string, date time.Time, err error) {
+	opts = opts.withDefaults()
+	if len(lines) != 1 {
+		return "", time.Time{}, newParseError(
+			CategoryIdentifier, 0, strings.Join(lines, "\n"),
+			fmt.Errorf("identifier must have one line, found %d", len(lines)),
+		)
+	}
 	line := lines[0]
+	id, date, err = parseIdentifierLine(line, opts.Reference, opts.Location)
+	if err != nil {
+		return "", time.Time{}, newParseError(CategoryIdentifier, 0, line, err)
+	}
+	return id, date, nil
+}
+
+// parseIdentifierLine reads the trailing "MM-DD HH:MM" of the line, the
+// minutes are optional. The date is taken on the closest year that does not
+// put it after the reference. The text before the date is returned as id.
+func parseIdentifierLine(line string, reference time.Time, loc *time.Location) (string, time.Time, error) {
 	hour := []byte{}
 	date := []byte{}
 	onHour := true
-	for i := len(line) - 1; i >= 0; i-- {
+	i := len(line) - 1
+	for ; i >= 0; i-- {
 		char := line[i]
 		if onHour {
 			if char == ' ' {
@@ -91,22 +199,61 @@ func ParseIdentifierNode(n *html.Node) (time.Time, error) {
 	slices.Reverse(hour)
 	hourSplitted := bytes.Split(hour, []byte(":"))
 	dateSplitted := bytes.Split(date, []byte("-"))
+	if len(dateSplitted) != 2 {
+		return "", time.Time{}, fmt.Errorf("invalid date %s", date)
+	}
 
 	hourNum, err := strconv.Atoi(string(hourSplitted[0]))
 	if err != nil {
-		return time.Time{}, fmt.Errorf("hour %w", err)
+		return "", time.Time{}, fmt.Errorf("hour %w", err)
+	}
+	minute := 0
+	if len(hourSplitted) > 1 {
+		minute, err = strconv.Atoi(string(hourSplitted[1]))
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("minute %w", err)
+		}
 	}
 	month, err := strconv.Atoi(string(dateSplitted[0]))
 	if err != nil {
-		return time.Time{}, fmt.Errorf("month %w", err)
+		return "", time.Time{}, fmt.Errorf("month %w", err)
 	}
 	day, err := strconv.Atoi(string(dateSplitted[1]))
 	if err != nil {
-		return time.Time{}, fmt.Errorf("day %w", err)
+		return "", time.Time{}, fmt.Errorf("day %w", err)
+	}
+
+	if month < 1 || month > 12 {
+		return "", time.Time{}, fmt.Errorf("month %d out of range", month)
+	}
+	// the year is not known yet, 2000 is a leap year so Feb 29 passes
+	if day < 1 || day > daysIn(time.Month(month), 2000) {
+		return "", time.Time{}, fmt.Errorf("day %d out of range for month %d", day, month)
+	}
+	if hourNum < 0 || hourNum > 23 {
+		return "", time.Time{}, fmt.Errorf("hour %d out of range", hourNum)
+	}
+	if minute < 0 || minute > 59 {
+		return "", time.Time{}, fmt.Errorf("minute %d out of range", minute)
 	}
 
-	// TODO where can i take the year??
-	t := time.Date(2024, time.Month(month), day, hourNum, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	// step back until the date is not after the reference and exists on
+	// the year, Feb 29 only exists on leap years
+	year := reference.In(loc).Year()
+	var t time.Time
+	for {
+		if day <= daysIn(time.Month(month), year) {
+			t = time.Date(year, time.Month(month), day, hourNum, minute, 0, 0, loc)
+			if !t.After(reference) {
+				break
+			}
+		}
+		year--
+	}
 	t = t.UTC()
-	return t, nil
+	return strings.TrimSpace(line[:max(i, 0)]), t, nil
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }
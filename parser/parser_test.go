@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// noPanic runs f returning the panic as an error
+func noPanic(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
+
+func TestBrokenDocumentsDoNotPanic(t *testing.T) {
+	files, err := filepath.Glob("testdata/broken/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("empty broken corpus")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			err := noPanic(func() error {
+				_, err := Parse(io.NopCloser(bytes.NewReader(data)))
+				return err
+			})
+			if err == nil {
+				t.Errorf("Parse expected error")
+			}
+			assertNotPanic(t, "Parse", err)
+
+			err = noPanic(func() error {
+				return Stream(bytes.NewReader(data), Options{Lenient: true}, StreamHandler{})
+			})
+			assertNotPanic(t, "Stream", err)
+
+			err = noPanic(func() error {
+				_, err := ParseAuto(bytes.NewReader(data), Options{Lenient: true})
+				return err
+			})
+			assertNotPanic(t, "ParseAuto", err)
+		})
+	}
+}
+
+func assertNotPanic(t *testing.T, name string, err error) {
+	t.Helper()
+	if err != nil && bytes.HasPrefix([]byte(err.Error()), []byte("panic: ")) {
+		t.Errorf("%s %s", name, err)
+	}
+}
+
+func TestBrokenDocumentsErrors(t *testing.T) {
+	tests := []struct {
+		file  string
+		err   error
+		turns int
+	}{
+		{file: "empty.html", err: ErrNoBattleLog},
+		{file: "404.html", err: ErrNoBattleLog},
+		{file: "unknown_layout.html", err: ErrUnsupportedLayout},
+		{file: "resume_only.html", err: ErrTruncatedLog},
+		{file: "cut_in_turn.html", err: ErrTruncatedLog, turns: 1},
+		{file: "cut_in_tag.html", err: ErrTruncatedLog, turns: 1},
+		{file: "p_cut.html", err: ErrTruncatedLog, turns: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata/broken", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(io.NopCloser(bytes.NewReader(data)))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v got %v", test.err, err)
+			}
+			if len(b.Turns) != test.turns {
+				t.Errorf("expected %d turns got %d", test.turns, len(b.Turns))
+			}
+
+			turns := 0
+			err = Stream(bytes.NewReader(data), Options{}, StreamHandler{
+				Turn: func(int, Turn) error {
+					turns++
+					return nil
+				},
+			})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Stream expected %v got %v", test.err, err)
+			}
+			if turns != test.turns {
+				t.Errorf("Stream expected %d turns got %d", test.turns, turns)
+			}
+		})
+	}
+}
+
+func TestBrokenDocumentsParseError(t *testing.T) {
+	for _, file := range []string{"bad_resume.html", "bad_identifier.html"} {
+		f, err := os.Open(filepath.Join("testdata/broken", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Parse(f)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s expected *ParseError got %v", file, err)
+		}
+	}
+}
+
+// testReference puts the dates of the testdata logs on 2024
+var testReference = time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+func TestParseIdentifierLine(t *testing.T) {
+	reference := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line string
+		date time.Time
+	}{
+		{line: "Battle log #1 05-30 10:15", date: time.Date(2025, time.May, 30, 10, 15, 0, 0, time.UTC)},
+		{line: "Battle log #1 12-31 23", date: time.Date(2024, time.December, 31, 23, 0, 0, 0, time.UTC)},
+		{line: "Battle log #1 02-29 12:00", date: time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		_, date, err := parseIdentifierLine(test.line, reference, time.UTC)
+		if err != nil {
+			t.Errorf("%s unexpected error %v", test.line, err)
+			continue
+		}
+		if !date.Equal(test.date) {
+			t.Errorf("%s expected %s got %s", test.line, test.date, date)
+		}
+	}
+
+	invalid := []string{"x 13-45 99:99", "x 00-10 10:00", "x 04-31 10:00", "x 02-30 10:00", "x 05-10 24:00", "x 05-10 10:60"}
+	for _, line := range invalid {
+		if _, _, err := parseIdentifierLine(line, reference, time.UTC); err == nil {
+			t.Errorf("%s expected error", line)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderCards returns the lines of each card of the battle, resume,
+// identifier and turns. Parsing the rendered log returns the same battle, as
+// long as the reference used to parse it puts the battle date on the right
+// year. The lines the parser does not read, as the second line of the resume
+// or the footer, have a fixed text that is not checked against a real log.
+func RenderCards(b Battle, opts Options) ([][]string, error) {
+	opts = opts.withDefaults()
+	cards := make([][]string, 0, len(b.Turns)+2)
+
+	if !b.Resume.Position.IsZero() {
+		resume, err := renderResume(b.Resume)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, resume)
+	}
+	cards = append(cards, []string{renderIdentifier(b, opts)})
+
+	for i, turn := range b.Turns {
+		lines, err := renderTurn(turn)
+		if err != nil {
+			return nil, fmt.Errorf("turn %d %w", i, err)
+		}
+		cards = append(cards, lines)
+	}
+	return cards, nil
+}
+
+// RenderText writes the battle as plain text, cards separated by an empty line
+func RenderText(w io.Writer, b Battle, opts Options) error {
+	cards, err := RenderCards(b, opts)
+	if err != nil {
+		return err
+	}
+	for i, card := range cards {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		for _, line := range card {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+const renderFooter = "Battle log end"
+
+// RenderHTML writes the battle with the card layout, or the p layout when
+// the battle has no resume.
+func RenderHTML(w io.Writer, b Battle, opts Options) error {
+	cards, err := RenderCards(b, opts)
+	if err != nil {
+		return err
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("<html><head><meta charset=\"utf-8\"></head><body>\n")
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			builder.WriteString(html.EscapeString(line))
+			builder.WriteString("<br>")
+		}
+	}
+
+	if b.Resume.Position.IsZero() {
+		builder.WriteString("<div class=\"card\">")
+		for _, card := range cards {
+			builder.WriteString("<p>")
+			writeLines(card)
+			builder.WriteString("</p>")
+		}
+		builder.WriteString("<p>" + renderFooter + "</p></div>\n")
+	} else {
+		for _, card := range cards {
+			builder.WriteString("<div class=\"card\">")
+			writeLines(card)
+			builder.WriteString("</div>\n")
+		}
+	}
+	builder.WriteString("<div class=\"card\">" + renderFooter + "</div>\n")
+	builder.WriteString("</body></html>\n")
+
+	_, err = io.WriteString(w, builder.String())
+	return err
+}
+
+func renderResume(r Resume) ([]string, error) {
+	lines := make([]string, 0, len(r.Teams)+2)
+	if r.Kind == BattleMonster {
+		lines = append(lines, "📯Battle with "+monsterResumePrefix()+" ["+r.Position.String()+"]")
+	} else {
+		castle := ""
+		if faction, ok := FactionByCode(r.Position.Team); ok && faction.ResumePrefix != "" {
+			castle = faction.ResumePrefix + " "
+		}
+		lines = append(lines, "📯Battle for "+castle+"["+r.Position.String()+"]")
+	}
+	lines = append(lines, "Participants:")
+	for _, team := range r.Teams {
+		faction, ok := FactionByCode(Team(team.Team))
+		if !ok || faction.ResumePrefix == "" {
+			return nil, fmt.Errorf("resume %w %c", ErrUnknownTeam, team.Team)
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%s: %d total, %d alive",
+			faction.ResumePrefix, team.Total, team.Alive,
+		))
+	}
+	return lines, nil
+}
+
+// monsterResumePrefix names the creatures on the header of monster battles
+func monsterResumePrefix() string {
+	for _, faction := range Factions() {
+		if faction.Monster && faction.ResumePrefix != "" {
+			return faction.ResumePrefix
+		}
+	}
+	return "👹Creatures"
+}
+
+func renderIdentifier(b Battle, opts Options) string {
+	date := b.Date.In(opts.Location).Format("01-02 15:04")
+	if b.ID == "" {
+		return date
+	}
+	return b.ID + " " + date
+}
+
+func renderTurn(t Turn) ([]string, error) {
+	lines := make([]string, 0, len(t.Strikes)+len(t.Events)+2)
+	lines = append(lines, t.Attacker.LogString()+" turn")
+	if t.Target.IsMiss() && len(t.Strikes) == 0 {
+		return append(lines, "waits"), nil
+	}
+
+	lines = append(lines, "target: "+t.Target.LogString()+" "+
+		strconv.Itoa(t.TargetHPBefore)+"HP, strikes: "+strconv.Itoa(t.DeclaredStrikes))
+	for _, strike := range t.Strikes {
+		line, err := renderStrike(strike, t.Target)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	for _, event := range t.Events {
+		lines = append(lines, event.String())
+	}
+	return lines, nil
+}
+
+// renderStrike writes the strike line, counter strikes are written with the
+// nick of the target that strikes back as "🔄Alice counter strike!"
+func renderStrike(s Strike, target User) (string, error) {
+	b := strings.Builder{}
+	if s.Counter {
+		b.WriteString(symbols["counterAttack"])
+	}
+	if s.Weakness {
+		b.WriteString(symbols["weaknessStrike"])
+	}
+	if s.Water {
+		b.WriteString(symbols["unkownWater"])
+	}
+	if s.Cross {
+		b.WriteString(symbols["unkownCruz"])
+	}
+	if s.Counter {
+		nick := target.Nick
+		if nick == "" {
+			nick = target.Name
+		}
+		b.WriteString(nick + " counter ")
+	}
+
+	switch s.Outcome {
+	case OutcomeMiss, OutcomeCounterMiss:
+		b.WriteString("miss!")
+	case OutcomeDodge:
+		b.WriteString("dodge!")
+	case OutcomeHit, OutcomeCounterHit:
+		if s.Crit {
+			b.WriteString("crit ")
+		}
+		b.WriteString("strike! dmg: " + strconv.Itoa(s.Damage) + ". Pdef was: " + strconv.Itoa(s.TargetDefense))
+	default:
+		return "", errors.New("unknown strike outcome " + s.Outcome.String())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRenderRoundTrip(t *testing.T) {
+	for _, file := range htmlLogs(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := Options{Reference: testReference}
+			b, err := ParseWithOptions(f, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			html := bytes.Buffer{}
+			if err := RenderHTML(&html, b, opts); err != nil {
+				t.Fatal(err)
+			}
+			fromHTML, err := ParseWithOptions(io.NopCloser(&html), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameBattle(t, "html", b, fromHTML)
+
+			text := bytes.Buffer{}
+			if err := RenderText(&text, b, opts); err != nil {
+				t.Fatal(err)
+			}
+			fromText, err := ParseText(&text, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameBattle(t, "text", b, fromText)
+		})
+	}
+}
+
+func assertSameBattle(t *testing.T, name string, expected Battle, actual Battle) {
+	t.Helper()
+	e, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := json.Marshal(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(e, a) || !reflect.DeepEqual(expected, actual) {
+		t.Errorf("%s battle differs\nexpected %s\nactual   %s", name, e, a)
+	}
+}
+
+// TestRenderMatchesLog checks the rendered lines against the testdata logs,
+// not only that the parser reads them back. See TestGolden for the lines of
+// those logs that are placeholders.
+func TestRenderMatchesLog(t *testing.T) {
+	for _, file := range []string{"territory.html", "monster.html"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata/logs", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := html.Parse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := make([]string, 0)
+			for _, card := range findCards(root) {
+				expected = append(expected, getNodeLines(card)...)
+			}
+
+			opts := Options{Reference: testReference}
+			b, err := ParseWithOptions(io.NopCloser(bytes.NewReader(data)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cards, err := RenderCards(b, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := make([]string, 0)
+			for _, card := range cards {
+				actual = append(actual, card...)
+			}
+			actual = append(actual, renderFooter)
+
+			if !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
@@ -1,58 +1,57 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"slices"
 	"strings"
 
-	"github.com/ross96D/battle-log-parser/assert"
 	"golang.org/x/net/html"
 )
 
-func ParseResumeNode(n *html.Node) Resume {
-	lines := getNodeLines(n)
+func ParseResumeNode(n *html.Node) (Resume, error) {
+	return ParseResumeLines(getNodeLines(n))
+}
+
+func ParseResumeLines(lines []string) (Resume, error) {
+	if len(lines) == 0 {
+		return Resume{}, newParseError(CategoryResume, 0, "", errors.New("empty resume"))
+	}
 
 	firstLine := lines[0]
 	if strings.HasPrefix(firstLine, "📯Battle with") {
-		return parseResumeNodeWithMonster(lines)
+		resume, err := parseResumeBody(lines)
+		if err != nil {
+			return Resume{}, err
+		}
+		resume.Kind = BattleMonster
+		return resume, nil
 	}
 
 	requiredFirstLine := "📯Battle for"
 
-	assert.Assert(
-		firstLine[0:len(requiredFirstLine)] == requiredFirstLine,
-		"required first line to be equal to %s instead was %s",
-		requiredFirstLine, firstLine[0:len(requiredFirstLine)],
-	)
-	assert.Assert(
-		firstLine[len(firstLine)-1] == ']',
-		"required first line to end on ] instead ended on %s",
-		firstLine[len(firstLine)-1],
-	)
-
-	position := make([]byte, 0, 4)
-	end := false
-	for i := len(firstLine) - 1; i >= 0 && !end; i-- {
-		char := firstLine[i]
-		switch char {
-		case ']', ' ':
-			continue
-		case '[':
-			end = true
-		default:
-			position = append(position, char)
-		}
+	if !strings.HasPrefix(firstLine, requiredFirstLine) {
+		return Resume{}, newParseError(
+			CategoryResume, 0, firstLine,
+			fmt.Errorf("required first line to start with %s", requiredFirstLine),
+		)
 	}
-	slices.Reverse(position)
-
-	resp := Resume{
-		Position: NewMapPosition(position),
-		Teams:    ParseResumeTeams(lines[2:]),
+	if firstLine[len(firstLine)-1] != ']' {
+		return Resume{}, newParseError(
+			CategoryResume, 0, firstLine,
+			fmt.Errorf("required first line to end on ] instead ended on %c", firstLine[len(firstLine)-1]),
+		)
 	}
 
-	return resp
+	resume, err := parseResumeBody(lines)
+	if err != nil {
+		return Resume{}, err
+	}
+	resume.Kind = BattleTerritory
+	return resume, nil
 }
 
-func parseResumeNodeWithMonster(lines []string) Resume {
+func parseResumeBody(lines []string) (Resume, error) {
 	firstLine := lines[0]
 
 	position := make([]byte, 0, 4)
@@ -70,69 +69,65 @@ func parseResumeNodeWithMonster(lines []string) Resume {
 	}
 	slices.Reverse(position)
 
+	pos, err := ParsePosition(string(position))
+	if err != nil {
+		return Resume{}, newParseError(CategoryResume, 0, firstLine, err)
+	}
+
+	if len(lines) < 2 {
+		return Resume{Position: pos}, nil
+	}
+	teams, err := ParseResumeTeams(lines[2:])
+	if err != nil {
+		return Resume{}, offsetLine(err, 2)
+	}
+
 	resp := Resume{
-		Position: NewMapPosition(position),
-		Teams:    ParseResumeTeams(lines[2:]),
+		Position: pos,
+		Teams:    teams,
 	}
-	return resp
+	return resp, nil
 }
 
-const greenPrefix = "🇲🇴Green Castle: "
-const yellowPrefix = "🇻🇦Yellow Castle: "
-const bluePrefix = "🇪🇺Blue Castle"
-const redPrefix = "🇮🇲Red Castle"
-const monsterPrefix = "👹Creatures"
-
-func ParseResumeTeams(lines []string) []ResumeTeam {
+func ParseResumeTeams(lines []string) ([]ResumeTeam, error) {
 
 	result := make([]ResumeTeam, 0)
 
-	parse := func(line string) (total, alive uint64) {
-		var err error
+	parse := func(line string) (total, alive uint64, err error) {
 		i := strings.Index(line, " total") - 1
-		assert.Assert(i != -1 && i != -2, "total not found in %s", line)
+		if i < 0 {
+			return 0, 0, errors.New("total not found")
+		}
 		total, err = parseNumBackwards(line, i)
-		assert.NoError(err)
+		if err != nil {
+			return 0, 0, fmt.Errorf("total %w", err)
+		}
 
 		i = strings.Index(line, " alive") - 1
-		assert.Assert(i != -1 && i != -2, "total not found in %s", line)
+		if i < 0 {
+			return 0, 0, errors.New("alive not found")
+		}
 		alive, err = parseNumBackwards(line, i)
-		assert.NoError(err)
+		if err != nil {
+			return 0, 0, fmt.Errorf("alive %w", err)
+		}
 
 		return
 	}
 
-	for _, line := range lines {
-		team := ResumeTeam{}
-		if line, ok := strings.CutPrefix(line, greenPrefix); ok {
-			total, alive := parse(line)
-			team.Team = 'G'
-			team.Alive = alive
-			team.Total = total
-		} else if line, ok := strings.CutPrefix(line, yellowPrefix); ok {
-			total, alive := parse(line)
-			team.Team = 'Y'
-			team.Alive = alive
-			team.Total = total
-		} else if line, ok := strings.CutPrefix(line, bluePrefix); ok {
-			total, alive := parse(line)
-			team.Team = 'B'
-			team.Alive = alive
-			team.Total = total
-		} else if line, ok := strings.CutPrefix(line, redPrefix); ok {
-			total, alive := parse(line)
-			team.Team = 'R'
-			team.Alive = alive
-			team.Total = total
-		} else if line, ok := strings.CutPrefix(line, monsterPrefix); ok {
-			total, alive := parse(line)
-			team.Team = 'M'
-			team.Alive = alive
-			team.Total = total
-		} else {
+	for i, line := range lines {
+		faction, rest, ok := factionByResumeLine(line)
+		if !ok {
 			break
 		}
+		team := ResumeTeam{Team: byte(faction.Code)}
+		total, alive, err := parse(rest)
+		if err != nil {
+			return nil, newParseError(CategoryResume, i, line, err)
+		}
+		team.Alive = alive
+		team.Total = total
 		result = append(result, team)
 	}
-	return result
+	return result, nil
 }
@@ -0,0 +1,86 @@
+package parser
+
+import "golang.org/x/net/html"
+
+// LogSource is a layout of the battle log page. Parse is only called on
+// documents the source detected.
+type LogSource interface {
+	Name() string
+	Detect(body *html.Node) bool
+	Parse(body *html.Node, opts Options) (Battle, error)
+}
+
+var sources = []LogSource{cardLayout{}, pLayout{}}
+
+// RegisterSource adds a layout that is checked before the ones already
+// registered. It is not safe to call it while parsing, use it on init. The
+// layout is used by Parse and ParseAuto, Stream only reads the built in ones.
+func RegisterSource(source LogSource) {
+	sources = append([]LogSource{source}, sources...)
+}
+
+// detectSource returns the first source that detects the document or nil
+func detectSource(body *html.Node) LogSource {
+	if body == nil {
+		return nil
+	}
+	for _, source := range sources {
+		if source.Detect(body) {
+			return source
+		}
+	}
+	return nil
+}
+
+func findCards(n *html.Node) []*html.Node {
+	return findAll(n, func(n *html.Node) bool {
+		if value, ok := attr(n, "class"); ok {
+			return value == "card"
+		}
+		return false
+	}, nil)
+}
+
+func findParagraphs(n *html.Node) []*html.Node {
+	return findAll(n, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "p"
+	}, nil)
+}
+
+// cardLayout has a card for the resume, one for the identifier, one for
+// each turn and a last one that is not a turn.
+type cardLayout struct{}
+
+func (cardLayout) Name() string {
+	return "card"
+}
+
+func (cardLayout) Detect(body *html.Node) bool {
+	cards := findCards(body)
+	if len(cards) == 0 {
+		return false
+	}
+	lines := getNodeLines(cards[0])
+	return len(lines) > 0 && isResumeLine(lines[0])
+}
+
+func (cardLayout) Parse(body *html.Node, opts Options) (Battle, error) {
+	return parseCards(nodesLines(findCards(body)), opts)
+}
+
+// pLayout has no resume, the first card holds a p for the identifier, one
+// for each turn and a last one that is not a turn.
+type pLayout struct{}
+
+func (pLayout) Name() string {
+	return "p"
+}
+
+func (pLayout) Detect(body *html.Node) bool {
+	cards := findCards(body)
+	return len(cards) > 0 && len(findParagraphs(cards[0])) > 0
+}
+
+func (pLayout) Parse(body *html.Node, opts Options) (Battle, error) {
+	return parseResumelessCards(nodesLines(findParagraphs(findCards(body)[0])), opts)
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// preLayout is a layout with the log as plain text inside a pre
+type preLayout struct{}
+
+func (preLayout) Name() string {
+	return "pre"
+}
+
+func findPre(body *html.Node) *html.Node {
+	return find(body, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "pre"
+	})
+}
+
+func (preLayout) Detect(body *html.Node) bool {
+	pre := findPre(body)
+	return pre != nil && pre.FirstChild != nil
+}
+
+func (preLayout) Parse(body *html.Node, opts Options) (Battle, error) {
+	return parseTextCards(textCards(findPre(body).FirstChild.Data), opts)
+}
+
+func TestRegisterSource(t *testing.T) {
+	registered := sources
+	t.Cleanup(func() {
+		sources = registered
+	})
+
+	data := []byte("<html><body><pre>📯Battle for 🇲🇴Green Castle [G3#4]\n" +
+		"Participants:\n🇲🇴Green Castle: 1 total, 1 alive\n\n" +
+		"Battle log #1 10-05 14:30\n\n" +
+		"🇲🇴Alice turn\ntarget: 🇻🇦Bob 120HP, strikes: 1\nmiss!\n\n" +
+		"end</pre></body></html>")
+	opts := Options{Reference: testReference}
+
+	_, err := ParseWithOptions(io.NopCloser(bytes.NewReader(data)), opts)
+	if !errors.Is(err, ErrNoBattleLog) {
+		t.Fatalf("expected ErrNoBattleLog before registering got %v", err)
+	}
+
+	RegisterSource(preLayout{})
+	b, err := ParseWithOptions(io.NopCloser(bytes.NewReader(data)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.ID != "Battle log #1" || len(b.Turns) != 1 || b.Turns[0].Attacker.Name != "Alice" {
+		t.Errorf("unexpected battle %+v", b)
+	}
+
+	// Stream only reads the built in layouts
+	err = Stream(bytes.NewReader(data), opts, StreamHandler{})
+	if !errors.Is(err, ErrNoBattleLog) {
+		t.Errorf("Stream expected ErrNoBattleLog got %v", err)
+	}
+}
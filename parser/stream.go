@@ -0,0 +1,304 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// StreamHandler receives the parts of the battle as soon as their card is
+// closed. Nil callbacks are ignored, returning an error stops the stream.
+type StreamHandler struct {
+	Resume     func(Resume) error
+	Identifier func(id string, date time.Time) error
+	// index is the position the turn would have on Battle.Turns
+	Turn func(index int, turn Turn) error
+	// only called on lenient mode
+	Warning func(Diagnostic) error
+}
+
+// Stream parses the battle log with a tokenizer instead of building the
+// whole document tree, calling the handler as each card is read.
+//
+// A turn is delivered as soon as its card closes. A card that is not a turn
+// is held until the next card, as the last card of the log is not a turn
+// and is left out. As Parse, it returns ErrNoBattleLog and ErrTruncatedLog,
+// the last after delivering every complete card.
+//
+// Only the card and p layouts are streamed. The layouts added with
+// RegisterSource need the whole document, Stream fails on them with
+// ErrNoBattleLog or ErrUnsupportedLayout: use Parse instead.
+func Stream(r io.Reader, opts Options, h StreamHandler) error {
+	s := streamer{
+		opts:    opts.withDefaults(),
+		handler: h,
+		card:    -1,
+	}
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return s.end()
+			}
+			return z.Err()
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+
+			if s.depth == 0 {
+				if isCard(z, hasAttr) {
+					s.openCard(tag)
+				}
+				continue
+			}
+
+			switch {
+			case tag == "br":
+				s.text.WriteByte('\n')
+			case tag == s.tag:
+				s.depth++
+			case tag == "p" && (s.card == 0 || s.pLayout):
+				// the p layout has no resume and every p on the first card
+				// is a turn. A p can be closed implicitly by the next one.
+				s.pLayout = true
+				if s.inP {
+					if err := s.closeP(); err != nil {
+						return err
+					}
+				}
+				s.inP = true
+				s.text.Reset()
+			}
+
+		case html.EndTagToken:
+			if s.depth == 0 {
+				continue
+			}
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case tag == "p" && s.inP:
+				if err := s.closeP(); err != nil {
+					return err
+				}
+			case tag == s.tag:
+				s.depth--
+				if s.depth == 0 {
+					if err := s.closeCard(); err != nil {
+						return err
+					}
+				}
+			}
+
+		case html.TextToken:
+			if s.depth > 0 && (!s.pLayout || s.inP) {
+				s.text.Write(z.Text())
+			}
+		}
+	}
+}
+
+func isCard(z *html.Tokenizer, hasAttr bool) bool {
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		if string(key) == "class" {
+			return string(val) == "card"
+		}
+	}
+	return false
+}
+
+// pendingCard is a closed card that is not a turn, kept until the next card
+// tells if it was the last one
+type pendingCard struct {
+	card  int
+	lines []string
+}
+
+type streamer struct {
+	opts    Options
+	handler StreamHandler
+
+	// tag of the open card and nesting of that tag inside the card
+	tag   string
+	depth int
+	// index of the current card, or p on the p layout
+	card int
+	text strings.Builder
+
+	pLayout bool
+	inP     bool
+	// number of p read on the p layout
+	ps int
+
+	pending *pendingCard
+	// the last closed card was a turn
+	lastTurn bool
+	turns    int
+}
+
+func (s *streamer) openCard(tag string) {
+	s.tag = tag
+	s.depth = 1
+	s.card++
+	s.text.Reset()
+}
+
+func (s *streamer) closeCard() error {
+	if s.pLayout {
+		if s.inP {
+			return s.closeP()
+		}
+		return nil
+	}
+
+	lines := splitLines(s.text.String())
+	switch s.card {
+	case 0:
+		if len(lines) == 0 || !isResumeLine(lines[0]) {
+			return ErrUnsupportedLayout
+		}
+		resume, err := ParseResumeLines(lines)
+		if err != nil {
+			return offsetCard(err, s.card)
+		}
+		if s.handler.Resume != nil {
+			return s.handler.Resume(resume)
+		}
+	case 1:
+		return s.identifier(lines, s.card)
+	default:
+		return s.closedTurnCard(lines, s.card)
+	}
+	return nil
+}
+
+func (s *streamer) closeP() error {
+	s.inP = false
+	lines := splitLines(s.text.String())
+	s.text.Reset()
+
+	index := s.ps
+	s.ps++
+	// cards after the one holding the p list are ignored
+	if s.card != 0 {
+		return nil
+	}
+	if index == 0 {
+		return s.identifier(lines, index)
+	}
+	return s.closedTurnCard(lines, index)
+}
+
+// end is called when the document ends. The last card must not be a turn,
+// otherwise the log was cut.
+func (s *streamer) end() error {
+	if s.card == -1 {
+		return ErrNoBattleLog
+	}
+
+	if s.pLayout {
+		// the card holding the p list was not closed
+		if s.card == 0 && s.depth > 0 {
+			if s.inP {
+				return s.cut(splitLines(s.text.String()))
+			}
+			return ErrTruncatedLog
+		}
+	} else {
+		// the card was not closed
+		if s.depth > 0 && s.card > 1 {
+			return s.cut(splitLines(s.text.String()))
+		}
+		if s.depth > 0 || s.card < 2 {
+			return ErrTruncatedLog
+		}
+	}
+
+	if s.lastTurn {
+		return ErrTruncatedLog
+	}
+	return nil
+}
+
+// cut handles the lines of the card that was not closed, the turn is
+// delivered only if it is complete, see completeTurn
+func (s *streamer) cut(lines []string) error {
+	if len(lines) == 0 {
+		return ErrTruncatedLog
+	}
+	if err := s.flushPending(); err != nil {
+		return err
+	}
+	if turn, ok := completeTurn(lines); ok {
+		if err := s.deliver(turn); err != nil {
+			return err
+		}
+	}
+	return ErrTruncatedLog
+}
+
+func (s *streamer) identifier(lines []string, card int) error {
+	id, date, err := ParseIdentifierLines(lines, s.opts)
+	if err != nil {
+		return offsetCard(err, card)
+	}
+	if s.handler.Identifier != nil {
+		return s.handler.Identifier(id, date)
+	}
+	return nil
+}
+
+// closedTurnCard delivers the turn of a closed card right away. A card that
+// is not a turn is kept as pending, it is parsed as a turn only when another
+// card follows it.
+func (s *streamer) closedTurnCard(lines []string, card int) error {
+	if err := s.flushPending(); err != nil {
+		return err
+	}
+	s.lastTurn = isTurnCard(lines)
+	if !s.lastTurn {
+		s.pending = &pendingCard{card: card, lines: lines}
+		return nil
+	}
+	return s.turn(lines, card)
+}
+
+// flushPending parses the pending card as a turn, as it was not the last one
+func (s *streamer) flushPending() error {
+	pending := s.pending
+	s.pending = nil
+	if pending == nil {
+		return nil
+	}
+	return s.turn(pending.lines, pending.card)
+}
+
+func (s *streamer) turn(lines []string, card int) error {
+	turn, warning, err := parseTurnCard(lines, card, s.opts)
+	if err != nil {
+		return err
+	}
+	if warning != nil {
+		if s.handler.Warning != nil {
+			return s.handler.Warning(*warning)
+		}
+		return nil
+	}
+	return s.deliver(turn)
+}
+
+func (s *streamer) deliver(turn Turn) error {
+	index := s.turns
+	s.turns++
+	if s.handler.Turn != nil {
+		return s.handler.Turn(index, turn)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamMatchesParse checks that the callbacks of Stream receive the
+// same battle as ParseWithOptions
+func TestStreamMatchesParse(t *testing.T) {
+	for _, file := range htmlLogs(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := Options{Reference: testReference}
+			expected, err := ParseWithOptions(io.NopCloser(bytes.NewReader(data)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := Battle{Turns: []Turn{}}
+			err = Stream(bytes.NewReader(data), opts, StreamHandler{
+				Resume: func(r Resume) error {
+					actual.Resume = r
+					return nil
+				},
+				Identifier: func(id string, date time.Time) error {
+					actual.ID, actual.Date = id, date
+					return nil
+				},
+				Turn: func(index int, turn Turn) error {
+					if index != len(actual.Turns) {
+						t.Errorf("expected turn %d got %d", len(actual.Turns), index)
+					}
+					actual.Turns = append(actual.Turns, turn)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			completeBattle(&actual)
+			assertSameBattle(t, "stream", expected, actual)
+		})
+	}
+}
+
+// TestStreamTurnOnClose checks that a turn is delivered when its card closes,
+// before the rest of the log is read
+func TestStreamTurnOnClose(t *testing.T) {
+	r, w := io.Pipe()
+	turns := make(chan Turn)
+	done := make(chan error)
+	go func() {
+		done <- Stream(r, Options{Reference: testReference}, StreamHandler{
+			Turn: func(_ int, turn Turn) error {
+				turns <- turn
+				return nil
+			},
+		})
+	}()
+
+	head := strings.Join([]string{
+		`<html><body>`,
+		`<div class="card">📯Battle for 🇲🇴Green Castle [G3#4]<br>Participants:<br>🇲🇴Green Castle: 1 total, 1 alive<br></div>`,
+		`<div class="card">Battle log #1 10-05 14:30</div>`,
+		`<div class="card">🇲🇴Alice turn<br>target: 🇻🇦Bob 120HP, strikes: 1<br>miss!<br></div>`,
+	}, "\n")
+	go io.WriteString(w, head)
+
+	select {
+	case turn := <-turns:
+		if turn.Attacker.Name != "Alice" {
+			t.Errorf("unexpected turn %+v", turn)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn not delivered when its card closed")
+	}
+
+	go func() {
+		io.WriteString(w, "\n<div class=\"card\">end</div></body></html>")
+		w.Close()
+	}()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// telegramText is the text of a message on a Telegram export, a string or a
+// list of strings and entities holding text.
+type telegramText string
+
+func (t *telegramText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = telegramText(s)
+		return nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(b, &parts); err != nil {
+		return err
+	}
+	builder := strings.Builder{}
+	for _, part := range parts {
+		var s string
+		if err := json.Unmarshal(part, &s); err == nil {
+			builder.WriteString(s)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err != nil {
+			return err
+		}
+		builder.WriteString(entity.Text)
+	}
+	*t = telegramText(builder.String())
+	return nil
+}
+
+type telegramMessage struct {
+	ID           int64        `json:"id"`
+	Type         string       `json:"type"`
+	DateUnixtime string       `json:"date_unixtime"`
+	Text         telegramText `json:"text"`
+}
+
+type telegramExport struct {
+	Messages []telegramMessage `json:"messages"`
+}
+
+// telegramLog is the text of a message that may hold a battle log
+type telegramLog struct {
+	id   string
+	date time.Time
+	text string
+}
+
+// ParseTelegramExport parses the JSON export of a Telegram chat, every
+// message that holds a battle log as text is a battle. The message date is
+// used as reference for the battle date instead of opts.Reference.
+func ParseTelegramExport(r io.Reader, opts Options) ([]Battle, error) {
+	export := telegramExport{}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("telegram export %w", err)
+	}
+
+	logs := make([]telegramLog, 0, len(export.Messages))
+	for _, message := range export.Messages {
+		if message.Type != "message" {
+			continue
+		}
+		log := telegramLog{
+			id:   strconv.FormatInt(message.ID, 10),
+			text: string(message.Text),
+		}
+		if unix, err := strconv.ParseInt(message.DateUnixtime, 10, 64); err == nil {
+			log.date = time.Unix(unix, 0)
+		}
+		logs = append(logs, log)
+	}
+	return parseTelegramLogs(logs, opts)
+}
+
+// ParseTelegramHTMLExport parses the HTML export of a Telegram chat, as
+// ParseTelegramExport does with the JSON export.
+func ParseTelegramHTMLExport(r io.Reader, opts Options) ([]Battle, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := findAll(root, func(n *html.Node) bool {
+		class, _ := attr(n, "class")
+		return hasClass(class, "message") && hasClass(class, "default")
+	}, nil)
+
+	logs := make([]telegramLog, 0, len(messages))
+	for _, message := range messages {
+		text := find(message, func(n *html.Node) bool {
+			class, _ := attr(n, "class")
+			return hasClass(class, "text")
+		})
+		if text == nil {
+			continue
+		}
+		log := telegramLog{
+			text: strings.Join(getNodeLines(text), "\n"),
+		}
+		log.id, _ = attr(message, "id")
+		date := find(message, func(n *html.Node) bool {
+			class, _ := attr(n, "class")
+			return hasClass(class, "date")
+		})
+		if date != nil {
+			title, _ := attr(date, "title")
+			if t, err := time.Parse("02.01.2006 15:04:05 UTC-07:00", title); err == nil {
+				log.date = t
+			}
+		}
+		logs = append(logs, log)
+	}
+	return parseTelegramLogs(logs, opts)
+}
+
+func hasClass(class string, name string) bool {
+	for _, c := range strings.Fields(class) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTelegramLogs(logs []telegramLog, opts Options) ([]Battle, error) {
+	result := make([]Battle, 0)
+	for _, log := range logs {
+		cards := textCards(log.text)
+		if !hasTurnCard(cards) {
+			continue
+		}
+
+		messageOpts := opts
+		if !log.date.IsZero() {
+			messageOpts.Reference = log.date
+		}
+		b, err := parseTextCards(cards, messageOpts.withDefaults())
+		if err != nil {
+			return nil, fmt.Errorf("message %s %w", log.id, err)
+		}
+		if b.Date.IsZero() {
+			b.Date = log.date.UTC()
+		}
+		completeBattle(&b)
+		result = append(result, b)
+	}
+	if len(result) == 0 {
+		return nil, ErrNoBattleLog
+	}
+	return result, nil
+}
+
+func hasTurnCard(cards [][]string) bool {
+	for _, card := range cards {
+		if isAttackerLine(card[0]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseText parses a battle log copied as plain text. Cards are separated
+// by empty lines, the resume, the identifier and the attacker line of each
+// turn also start a new card, so logs pasted without empty lines still work.
+func ParseText(r io.Reader, opts Options) (Battle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Battle{}, err
+	}
+	b, err := parseTextCards(textCards(string(data)), opts.withDefaults())
+	if err != nil {
+		return Battle{}, err
+	}
+	if !hasHeader(b) && len(b.Turns) == 0 && len(b.Warnings) == 0 {
+		return Battle{}, ErrNoBattleLog
+	}
+	completeBattle(&b)
+	return b, nil
+}
+
+// hasHeader reports whether the battle has a resume or identifier
+func hasHeader(b Battle) bool {
+	return b.ID != "" || !b.Date.IsZero() || !b.Resume.Position.IsZero()
+}
+
+func isResumeLine(line string) bool {
+	return strings.HasPrefix(line, "📯Battle")
+}
+
+func isAttackerLine(line string) bool {
+	return strings.HasSuffix(line, " turn")
+}
+
+func isIdentifierLine(line string) bool {
+	_, _, err := parseIdentifierLine(line, time.Now(), time.UTC)
+	return err == nil
+}
+
+func textCards(text string) [][]string {
+	cards := make([][]string, 0)
+	card := make([]string, 0)
+	flush := func() {
+		if len(card) > 0 {
+			cards = append(cards, card)
+			card = make([]string, 0)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+		if isResumeLine(line) || isAttackerLine(line) {
+			flush()
+		}
+		if isIdentifierLine(line) {
+			flush()
+			cards = append(cards, []string{line})
+			continue
+		}
+		card = append(card, line)
+	}
+	flush()
+	return cards
+}
+
+// parseTextCards identifies each card by its content as there is no layout
+// to rely on. The first card with a single line that holds a date is the
+// identifier, cards that are not resume, identifier or turn are ignored.
+func parseTextCards(cards [][]string, opts Options) (b Battle, err error) {
+	identified := false
+	for i, card := range cards {
+		switch {
+		case isResumeLine(card[0]):
+			b.Resume, err = ParseResumeLines(card)
+			if err != nil {
+				return Battle{}, offsetCard(err, i)
+			}
+
+		case isAttackerLine(card[0]):
+			turn, warning, err := parseTurnCard(card, i, opts)
+			if err != nil {
+				return Battle{}, err
+			}
+			if warning != nil {
+				b.Warnings = append(b.Warnings, *warning)
+				continue
+			}
+			b.Turns = append(b.Turns, turn)
+
+		case !identified && len(card) == 1:
+			id, date, err := ParseIdentifierLines(card, opts)
+			if err != nil {
+				continue
+			}
+			b.ID, b.Date = id, date
+			identified = true
+		}
+	}
+	if b.Turns == nil {
+		b.Turns = make([]Turn, 0)
+	}
+	return b, nil
+}
@@ -1,110 +1,299 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 
-	"github.com/ross96D/battle-log-parser/assert"
 	"golang.org/x/net/html"
 )
 
-func ParseTurnNodes(nodes []*html.Node) []Turn {
+func ParseTurnNodes(nodes []*html.Node) ([]Turn, error) {
 	result := make([]Turn, 0, len(nodes))
-	for _, n := range nodes {
-		result = append(result, ParseTurnNode(n))
+	for i, n := range nodes {
+		turn, err := ParseTurnNode(n)
+		if err != nil {
+			return nil, offsetCard(err, i)
+		}
+		result = append(result, turn)
 	}
-	return result
+	return result, nil
+}
+
+// parseTurnCards parses the lines of the turn cards, offset is the index of
+// the first card on the document. On lenient mode the cards that fail are
+// skipped and reported as diagnostics.
+func parseTurnCards(cards [][]string, offset int, opts Options) ([]Turn, []Diagnostic, error) {
+	result := make([]Turn, 0, len(cards))
+	var warnings []Diagnostic
+	for i, lines := range cards {
+		turn, warning, err := parseTurnCard(lines, offset+i, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if warning != nil {
+			warnings = append(warnings, *warning)
+			continue
+		}
+		result = append(result, turn)
+	}
+	return result, warnings, nil
+}
+
+// parseLogTurns parses the turn cards of a log that ends with a card that
+// is not a turn. When the last card is a turn, or there are no cards, the
+// log was cut: the turns are parsed anyway dropping the last one if it is
+// incomplete, see completeTurn.
+func parseLogTurns(cards [][]string, offset int, opts Options) (turns []Turn, warnings []Diagnostic, truncated bool, err error) {
+	if len(cards) == 0 {
+		return []Turn{}, nil, true, nil
+	}
+
+	last := cards[len(cards)-1]
+	turns, warnings, err = parseTurnCards(cards[:len(cards)-1], offset, opts)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !isTurnCard(last) {
+		return turns, warnings, false, nil
+	}
+
+	if turn, ok := completeTurn(last); ok {
+		turns = append(turns, turn)
+	}
+	return turns, warnings, true, nil
+}
+
+// completeTurn parses the last card of a cut log. A turn without target may
+// be a turn cut after the attacker line, so only turns with strikes are kept.
+func completeTurn(lines []string) (Turn, bool) {
+	if len(lines) < 3 {
+		return Turn{}, false
+	}
+	turn, err := ParseTurnLines(lines)
+	return turn, err == nil
+}
+
+func isTurnCard(lines []string) bool {
+	return len(lines) > 0 && isAttackerLine(lines[0])
 }
 
-func ParseTurnNode(n *html.Node) Turn {
-	lines := getNodeLines(n)
+// parseTurnCard parses the lines of the turn at the card index. On lenient
+// mode a failure is returned as a diagnostic instead of an error.
+func parseTurnCard(lines []string, card int, opts Options) (Turn, *Diagnostic, error) {
+	turn, err := ParseTurnLines(lines)
+	if err == nil && opts.Strict {
+		err = validateTurn(turn, lines)
+	}
+	if err == nil {
+		return turn, nil, nil
+	}
+	err = offsetCard(err, card)
+	if !opts.Lenient {
+		return Turn{}, nil, err
+	}
+	return Turn{}, &Diagnostic{
+		Card:   card,
+		Lines:  lines,
+		Reason: err.Error(),
+	}, nil
+}
+
+// validateTurn checks on strict mode what ParseTurnLines only records
+func validateTurn(turn Turn, lines []string) error {
+	if turn.StrikesMismatch {
+		return newParseError(
+			CategoryTurn, 1, lines[1],
+			fmt.Errorf("declared %d strikes but found %d", turn.DeclaredStrikes, turn.ownStrikes()),
+		)
+	}
+	return nil
+}
+
+func ParseTurnNode(n *html.Node) (Turn, error) {
+	return ParseTurnLines(getNodeLines(n))
+}
+
+func ParseTurnLines(lines []string) (Turn, error) {
+	if len(lines) < 2 {
+		return Turn{}, newParseError(CategoryTurn, len(lines), "", errors.New("missing target line"))
+	}
+
 	attackerLine := lines[0]
+	attacker, err := parseAttackerLine(attackerLine)
+	if err != nil {
+		return Turn{}, newParseError(CategoryTurn, 0, attackerLine, err)
+	}
 
 	if len(lines) == 2 {
 		return Turn{
-			Attacker: parseAttackerLine(attackerLine),
-		}
+			Attacker: attacker,
+		}, nil
 	}
 
 	targetLine := lines[1]
-	strikesLines := strikeLines(lines[2:])
+	target, hp, declared, err := parseTargeLine(targetLine)
+	if err != nil {
+		return Turn{}, newParseError(CategoryTurn, 1, targetLine, err)
+	}
 
-	return Turn{
-		Attacker: parseAttackerLine(attackerLine),
-		Target:   parseTargeLine(targetLine),
-		Strikes:  parseStrikesLines(strikesLines),
+	strikesLines, eventLines, err := strikeLines(lines[2:])
+	if err != nil {
+		return Turn{}, newParseError(CategoryTurn, 2, lines[2], err)
 	}
+	strikes, err := parseStrikesLines(strikesLines)
+	if err != nil {
+		return Turn{}, offsetLine(err, 2)
+	}
+	events, err := parseTurnEventLines(eventLines)
+	if err != nil {
+		return Turn{}, offsetLine(err, 2+len(strikesLines))
+	}
+
+	turn := Turn{
+		Attacker:        attacker,
+		Target:          target,
+		Strikes:         strikes,
+		TargetHPBefore:  hp,
+		DeclaredStrikes: declared,
+		Events:          events,
+	}
+	turn.StrikesMismatch = turn.DeclaredStrikes != turn.ownStrikes()
+	turn.TargetHPAfter = max(turn.TargetHPBefore-turn.Damage(), 0)
+	turn.TargetDied = turn.TargetHPBefore > 0 && turn.TargetHPAfter == 0
+
+	return turn, nil
 }
 
-func parseAttackerLine(line string) (u User) {
-	assert.Assert(line != "")
+func findDeaths(turns []Turn) []Death {
+	result := make([]Death, 0)
+	for i, turn := range turns {
+		if turn.TargetDied {
+			result = append(result, Death{
+				User:   turn.Target,
+				Killer: turn.Attacker,
+				Turn:   i,
+			})
+		}
+	}
+	return result
+}
+
+func parseAttackerLine(line string) (User, error) {
 	line, ok := strings.CutSuffix(line, " turn")
-	assert.Assert(ok)
+	if !ok {
+		return User{}, errors.New("attacker line must end with \" turn\"")
+	}
 	return UserFromString(line)
 }
 
-func parseTargeLine(line string) User {
-	assert.Assert(line != "")
+func parseTargeLine(line string) (u User, hp int, strikes int, err error) {
 	line, ok := strings.CutPrefix(line, "target: ")
-	assert.Assert(ok)
+	if !ok {
+		return User{}, 0, 0, errors.New("target line must start with \"target: \"")
+	}
 
 	// get name string
-	i := strings.Index(line, "HP, strikes: ")
-	assert.Assert(i != -1)
+	hpEnd := strings.Index(line, "HP, strikes: ")
+	if hpEnd == -1 {
+		return User{}, 0, 0, errors.New("target line missing \"HP, strikes: \"")
+	}
+	i := hpEnd
 	for ; i >= 0; i-- {
 		if line[i] == ' ' {
 			break
 		}
 	}
+	if i < 0 {
+		return User{}, 0, 0, errors.New("target line missing space before HP")
+	}
+
+	hp, err = strconv.Atoi(line[i+1 : hpEnd])
+	if err != nil {
+		return User{}, 0, 0, fmt.Errorf("target hp %w", err)
+	}
+	strikes, err = strconv.Atoi(strings.TrimSpace(line[hpEnd+len("HP, strikes: "):]))
+	if err != nil {
+		return User{}, 0, 0, fmt.Errorf("target strikes %w", err)
+	}
 
-	return UserFromString(line[0:i])
+	u, err = UserFromString(line[0:i])
+	return u, hp, strikes, err
 }
 
-func parseStrikesLines(lines []string) []Strike {
+func parseStrikesLines(lines []string) ([]Strike, error) {
 	if len(lines) == 0 {
-		return []Strike{}
+		return []Strike{}, nil
 	}
 	result := make([]Strike, 0, len(lines))
-	for _, line := range lines {
-		strike, ok := parseStrikeLine(line)
-		if !ok {
-			continue
+	for i, line := range lines {
+		strike, err := parseStrikeLine(line)
+		if err != nil {
+			return nil, newParseError(CategoryStrike, i, line, err)
 		}
 		result = append(result, strike)
 	}
-	return result
+	return result, nil
 }
 
-func parseStrikeLine(line string) (Strike, bool) {
-	origLine := line
-
-	line, modifier := stripSymbols(line)
-	if modifier == counter {
-		index := strings.Index(line, "strike! dmg:")
-		// TODO miss on counter
+func parseStrikeLine(line string) (Strike, error) {
+	line, modifiers := stripSymbols(line)
+	if modifiers.has(counter) {
+		index := strings.Index(line, "crit strike! dmg:")
 		if index == -1 {
-			return Strike{}, false
+			index = strings.Index(line, "strike! dmg:")
 		}
-		line = line[strings.Index(line, "strike! dmg:"):]
+		if index == -1 {
+			switch {
+			case strings.HasSuffix(line, "miss!"):
+				return Strike{Outcome: OutcomeCounterMiss, Counter: true}, nil
+			case strings.Contains(line, "dodge"):
+				return Strike{Outcome: OutcomeDodge, Counter: true}, nil
+			default:
+				return Strike{}, errors.New("unknown counter strike")
+			}
+		}
+		line = line[index:]
 	}
-	if line == "miss!" {
-		return Strike{}, true
+	switch line {
+	case "miss!":
+		return Strike{Outcome: OutcomeMiss}, nil
+	case "dodge!", "dodged!":
+		return Strike{Outcome: OutcomeDodge}, nil
 	}
 
-	line, ok := cutPrefixStrikeLines(line)
-	assert.Assert(ok, origLine)
+	line, crit, ok := cutPrefixStrikeLines(line)
+	if !ok {
+		return Strike{}, errors.New("unknown strike")
+	}
 	splitted := strings.Split(line, ". Pdef was: ")
-	assert.Assert(len(splitted) == 2)
+	if len(splitted) != 2 {
+		return Strike{}, errors.New("strike missing \". Pdef was: \"")
+	}
 
 	dmg, err := strconv.Atoi(splitted[0])
-	assert.NoError(err)
+	if err != nil {
+		return Strike{}, fmt.Errorf("damage %w", err)
+	}
 	defense, err := strconv.Atoi(splitted[1])
-	assert.NoError(err)
+	if err != nil {
+		return Strike{}, fmt.Errorf("defense %w", err)
+	}
 
 	strike := Strike{}
+	strike.Outcome = OutcomeHit
+	if modifiers.has(counter) {
+		strike.Outcome = OutcomeCounterHit
+	}
 	strike.Damage = dmg
 	strike.TargetDefense = defense
-	return strike, true
+	strike.Crit = crit
+	strike.Weakness = modifiers.has(weakness)
+	strike.Water = modifiers.has(water)
+	strike.Cross = modifiers.has(cross)
+	strike.Counter = modifiers.has(counter)
+	return strike, nil
 }
 
 var symbols map[string]string = map[string]string{
@@ -114,6 +303,7 @@ var symbols map[string]string = map[string]string{
 	"counterAttack":  "🔄",
 }
 
+// attacksModifiers is a set of the symbols found at the begining of a strike line
 type attacksModifiers int
 
 func (attacksModifiers) fromStr(s string) attacksModifiers {
@@ -121,55 +311,61 @@ func (attacksModifiers) fromStr(s string) attacksModifiers {
 	case "weaknessStrike":
 		return weakness
 	case "unkownWater":
-		return unkown
+		return water
 	case "unkownCruz":
-		return unkown
+		return cross
 	case "counterAttack":
 		return counter
 	default:
-		return unkown
+		return none
 	}
 }
 
+func (m attacksModifiers) has(modifier attacksModifiers) bool {
+	return m&modifier != 0
+}
+
 const (
-	none attacksModifiers = iota - 1
-	unkown
-	weakness
+	none     attacksModifiers = 0
+	weakness attacksModifiers = 1 << (iota - 1)
+	water
+	cross
 	counter
 )
 
-// TODO add value
+// stripSymbols removes all the modifier symbols at the begining of the line
 func stripSymbols(line string) (string, attacksModifiers) {
-	for k, v := range symbols {
-		l := len(v)
-		if l >= len(line) {
-			continue
-		}
-		prefix := line[0:l]
-		if v == prefix {
-			return line[l:], attacksModifiers(0).fromStr(k)
+	modifiers := none
+	for stripped := true; stripped; {
+		stripped = false
+		for k, v := range symbols {
+			l := len(v)
+			if l >= len(line) {
+				continue
+			}
+			if v == line[0:l] {
+				line = line[l:]
+				modifiers |= attacksModifiers(0).fromStr(k)
+				stripped = true
+			}
 		}
 	}
-	return line, none
+	return line, modifiers
 }
 
-func cutPrefixStrikeLines(line string) (string, bool) {
+func cutPrefixStrikeLines(line string) (rest string, crit bool, ok bool) {
 	if line, ok := strings.CutPrefix(line, "strike! dmg: "); ok {
-		return line, true
+		return line, false, true
 	}
 	if line, ok := strings.CutPrefix(line, "crit strike! dmg: "); ok {
-		return line, true
-	}
-	if line, ok := strings.CutPrefix(line, "💦strike! dmg: "); ok {
-		return line, true
-	}
-	if line, ok := strings.CutPrefix(line, "💦crit strike! dmg: "); ok {
-		return line, true
+		return line, true, true
 	}
-	return line, false
+	return line, false, false
 }
 
-func strikeLines(lines []string) []string {
+// strikeLines splits the lines after the target line on the strikes and the
+// trailing events (team flag announcements and retrieved arrows)
+func strikeLines(lines []string) (strikes []string, events []string, err error) {
 	end := len(lines)
 	for i := len(lines) - 1; i >= 0; i-- {
 		line := lines[i]
@@ -177,14 +373,46 @@ func strikeLines(lines []string) []string {
 			end--
 			continue
 		}
-		if strings.HasSuffix(line, "retrieved an arrow") {
+		if strings.HasSuffix(line, arrowRetrievedSuffix) {
 			end--
 			continue
 		}
 		break
 	}
-	assert.Assert(end > 0, strconv.Itoa(end)+": "+strings.Join(lines, "\n"))
-	return lines[:end]
+	if end <= 0 {
+		return nil, nil, errors.New("turn without strikes")
+	}
+	return lines[:end], lines[end:], nil
+}
+
+const arrowRetrievedSuffix = " retrieved an arrow"
+
+func parseTurnEventLines(lines []string) ([]TurnEvent, error) {
+	result := make([]TurnEvent, 0, len(lines))
+	for i, line := range lines {
+		event, err := parseTurnEventLine(line)
+		if err != nil {
+			return nil, newParseError(CategoryTurn, i, line, err)
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+func parseTurnEventLine(line string) (TurnEvent, error) {
+	if name, ok := strings.CutSuffix(line, arrowRetrievedSuffix); ok {
+		user, err := UserFromString(name)
+		if err != nil {
+			user = User{Name: name}
+		}
+		return TurnEvent{Kind: EventArrowRetrieved, User: user}, nil
+	}
+
+	team, size, err := TeamFromRune(line)
+	if err != nil {
+		return TurnEvent{}, err
+	}
+	return TurnEvent{Kind: EventFlag, Team: team, Text: line[size:]}, nil
 }
 
 func hasFlagAtBegining(line string) bool {
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTurnCounters(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		hits, misses  int
+		counterHits   int
+		counterMisses int
+		damage        int
+		crits         int
+		weaknesses    int
+		counterDamage int
+	}{
+		{
+			name: "counter hit",
+			lines: []string{
+				"🇻🇦Bob turn",
+				"target: 🇲🇴[ABC]Alice 90HP, strikes: 1",
+				"⚡️strike! dmg: 12. Pdef was: 3",
+				"🔄Alice counter strike! dmg: 4. Pdef was: 1",
+			},
+			hits: 1, counterHits: 1, damage: 12, weaknesses: 1, counterDamage: 4,
+		},
+		{
+			name: "counter crit",
+			lines: []string{
+				"🇻🇦Bob turn",
+				"target: 🇲🇴Alice 90HP, strikes: 1",
+				"strike! dmg: 10. Pdef was: 3",
+				"🔄⚡️Alice counter crit strike! dmg: 20. Pdef was: 1",
+			},
+			hits: 1, counterHits: 1, damage: 10, counterDamage: 20,
+		},
+		{
+			name: "counter miss",
+			lines: []string{
+				"🇻🇦Dave turn",
+				"target: 🇪🇺[XYZ]🔥Carol 40HP, strikes: 1",
+				"miss!",
+				"🔄Carol counter miss!",
+			},
+			misses: 1, counterMisses: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			turn, err := ParseTurnLines(test.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if turn.Hits() != test.hits || turn.Misses() != test.misses {
+				t.Errorf("expected %d/%d hits/misses got %d/%d", test.hits, test.misses, turn.Hits(), turn.Misses())
+			}
+			if turn.CounterHits() != test.counterHits || turn.CounterMisses() != test.counterMisses {
+				t.Errorf("expected %d/%d counter hits/misses got %d/%d", test.counterHits, test.counterMisses, turn.CounterHits(), turn.CounterMisses())
+			}
+			if turn.Damage() != test.damage || turn.CounterDamage() != test.counterDamage {
+				t.Errorf("expected %d/%d damage/counter damage got %d/%d", test.damage, test.counterDamage, turn.Damage(), turn.CounterDamage())
+			}
+			if turn.Crits() != test.crits || turn.Weaknesses() != test.weaknesses {
+				t.Errorf("expected %d/%d crits/weaknesses got %d/%d", test.crits, test.weaknesses, turn.Crits(), turn.Weaknesses())
+			}
+			b := Battle{Turns: []Turn{turn}}
+			damage := b.PlayerListWithDamage()
+			if damage[turn.Attacker] != test.damage || damage[turn.Target] != test.counterDamage {
+				t.Errorf("unexpected player damage %v", damage)
+			}
+		})
+	}
+
+	if (Strike{}).IsHit() || (Strike{}).IsMiss() {
+		t.Errorf("zero value strike must be neither hit nor miss")
+	}
+}
+
+func TestStrikesMismatch(t *testing.T) {
+	lines := []string{
+		"🇻🇦Bob turn",
+		"target: 🇲🇴Alice 90HP, strikes: 2",
+		"strike! dmg: 12. Pdef was: 3",
+	}
+
+	turn, warning, err := parseTurnCard(lines, 2, Options{})
+	if err != nil || warning != nil {
+		t.Fatalf("expected the turn got %v %v", warning, err)
+	}
+	if !turn.StrikesMismatch {
+		t.Errorf("expected the mismatch on the turn")
+	}
+
+	_, _, err = parseTurnCard(lines, 2, Options{Strict: true})
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Card != 2 || perr.Line != 1 {
+		t.Errorf("expected *ParseError on card 2 line 1 got %v", err)
+	}
+
+	_, warning, err = parseTurnCard(lines, 2, Options{Strict: true, Lenient: true})
+	if err != nil || warning == nil {
+		t.Errorf("expected a warning got %v %v", warning, err)
+	}
+}
+
+func TestUnknownCounterStrike(t *testing.T) {
+	lines := []string{
+		"🇻🇦Bob turn",
+		"target: 🇲🇴Alice 90HP, strikes: 1",
+		"strike! dmg: 12. Pdef was: 3",
+		"🔄Alice counter parry",
+	}
+
+	_, _, err := parseTurnCard(lines, 2, Options{})
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Category != CategoryStrike {
+		t.Errorf("expected strike *ParseError got %v", err)
+	}
+
+	_, warning, err := parseTurnCard(lines, 2, Options{Lenient: true})
+	if err != nil || warning == nil {
+		t.Errorf("expected a warning got %v %v", warning, err)
+	}
+}
@@ -1,63 +1,52 @@
 package parser
 
 import (
-	"bytes"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/ross96D/battle-log-parser/assert"
 )
 
-var yellowRune = "🇻🇦"
-var greenRune = "🇲🇴"
-var blueRune = "🇪🇺"
-var redRune = "🇮🇲"
-var monsterRuneOnTurn = "⚱️"
-var monsterRuneOnResume = "👹"
-
+// Team is the code of a faction, see Faction. The zero Team is used for
+// the target of turns without target.
 type Team byte
 
+// ErrUnknownTeam is returned for teams that are not registered as factions
+const ErrUnknownTeam Error = "unknown team"
+
 func (t Team) Name() string {
-	switch t {
-	case 'G':
-		return "Green"
-	case 'Y':
-		return "Yellow"
-	case 'B':
-		return "Blue"
-	case 'R':
-		return "Red"
-	case 'M':
-		return "Monster"
-	case 0:
+	if t == 0 {
 		return "Miss"
-	default:
-		panic("unknow team " + string(t))
 	}
+	if faction, ok := FactionByCode(t); ok {
+		return faction.Name
+	}
+	return "Unknown " + string(t)
 }
 
 func (t Team) String() string {
-	switch t {
-	case 'G':
-		return "🇲🇴"
-	case 'Y':
-		return "🇻🇦"
-	case 'B':
-		return "🇪🇺"
-	case 'R':
-		return "🇮🇲"
-	case 'M':
-		return "👹"
-	case 0:
+	if t == 0 {
 		return "Miss"
-	default:
-		panic("unknow team " + string(t))
 	}
+	if faction, ok := FactionByCode(t); ok {
+		return faction.Flag
+	}
+	return "Unknown " + string(t)
+}
+
+// logFlag is the flag of the team as written before the players on the turns
+func (t Team) logFlag() string {
+	if faction, ok := FactionByCode(t); ok {
+		return faction.turnFlag()
+	}
+	return t.String()
 }
 
 func (t Team) MarshalJSON() ([]byte, error) {
+	if _, ok := FactionByCode(t); !ok && t != 0 {
+		return nil, fmt.Errorf("%w %c", ErrUnknownTeam, t)
+	}
 	return []byte("\"" + t.String() + "\""), nil
 }
 
@@ -65,45 +54,32 @@ func (t *Team) UnmarshalJSON(b []byte) error {
 	s := strings.TrimFunc(string(b), func(r rune) bool {
 		return r == '"'
 	})
-	switch s {
-	case "Green", "🇲🇴":
-		*t = 'G'
-	case "Yellow", "🇻🇦":
-		*t = 'Y'
-	case "Red", "🇮🇲":
-		*t = 'R'
-	case "Blue", "🇪🇺":
-		*t = 'B'
-	case "Monster", "👹":
-		*t = 'M'
-	case "Miss":
+	if s == "Miss" {
 		*t = 0
-	default:
-		return errors.New("invalid team " + string(b))
+		return nil
+	}
+	faction, ok := factionByName(s)
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownTeam, string(b))
 	}
+	*t = faction.Code
 	return nil
 }
 
+// TeamFromRune reads the team flag at the begining of r, returning the team
+// and the length in bytes of the flag. The flag is a grapheme cluster made
+// of many runes.
 func TeamFromRune(r string) (Team, int, error) {
-	if strings.HasPrefix(r, yellowRune) {
-		return 'Y', len(yellowRune), nil
-	}
-	if strings.HasPrefix(r, greenRune) {
-		return 'G', len(greenRune), nil
-	}
-	if strings.HasPrefix(r, blueRune) {
-		return 'B', len(blueRune), nil
-	}
-	if strings.HasPrefix(r, redRune) {
-		return 'R', len(redRune), nil
-	}
-	if strings.HasPrefix(r, monsterRuneOnResume) {
-		return 'M', len(monsterRuneOnResume), nil
+	size := graphemeLen(r)
+	if size == 0 {
+		return 0, 0, fmt.Errorf("%w, empty string", ErrUnknownTeam)
 	}
-	if strings.HasPrefix(r, monsterRuneOnTurn) {
-		return 'M', len(monsterRuneOnTurn), nil
+	flag := r[:size]
+	faction, ok := factionByFlag(flag)
+	if !ok {
+		return 0, 0, fmt.Errorf("%w %s", ErrUnknownTeam, flag)
 	}
-	return 0, 0, errors.New("unidentify rune " + string(r))
+	return faction.Code, size, nil
 }
 
 func MustTeamFromRune(r string) (Team, int) {
@@ -117,77 +93,189 @@ func MustTeamFromRune(r string) (Team, int) {
 type User struct {
 	Team Team   `json:"team"`
 	Name string `json:"name"`
+	// guild tag and nick taken from the name, see SplitName
+	Guild string `json:"guild"`
+	Nick  string `json:"nick"`
 }
 
 func (u User) String() string {
 	return u.Team.String() + " " + u.Name
 }
 
+// Key identifies the user across battles by team, guild tag and nick,
+// ignoring the emoji decoration of the name that players change often.
+// Players with the same nick on different guilds are different users.
+func (u User) Key() User {
+	nick, guild := u.Nick, u.Guild
+	if nick == "" {
+		parts := SplitName(u.Name)
+		nick, guild = parts.Nick, parts.Guild
+	}
+	if nick == "" {
+		return u
+	}
+	return User{Team: u.Team, Guild: guild, Nick: nick}
+}
+
+// LogString is the user as written on the log, the flag followed by the name
+func (u User) LogString() string {
+	if u.Team == 0 {
+		return u.Name
+	}
+	return u.Team.logFlag() + u.Name
+}
+
 func (u User) IsMiss() bool {
 	return u == User{}
 }
 
-func UserFromString(s string) (u User) {
-	// TODO flag icon is composed of 2 runes but i work as if it is one
-	// r, size := utf8.DecodeRune([]byte(s))
-	// firstChar := mutf16.StringToUTF16(s)[0]
-	// assert.Assert(r != utf8.RuneError)
-	team, size := MustTeamFromRune(s)
+func UserFromString(s string) (u User, err error) {
+	team, size, err := TeamFromRune(s)
+	if err != nil {
+		return User{}, err
+	}
 	u.Team = team
 	u.Name = s[size:]
-	return u
+	parts := SplitName(u.Name)
+	u.Guild = parts.Guild
+	u.Nick = parts.Nick
+	return u, nil
 }
 
+type PositionKind byte
+
+const (
+	// tile inside a castle territory, ex: G3#4
+	PositionInner PositionKind = iota
+	// tile on the edge shared with another castle, ex: GY5
+	PositionEdge
+	// tile on the corner shared with another castle, ex: GY
+	PositionCorner
+)
+
 type Position struct {
 	Team Team
-	Y    uint64
-	X    uint64
+	Kind PositionKind
+	// castle the edge or corner is shared with
+	Neighbour Team
+	Y         uint64
+	X         uint64
+}
+
+func (p Position) IsZero() bool {
+	return p == Position{}
 }
 
 func (p Position) String() string {
+	if p.IsZero() {
+		return ""
+	}
 	b := strings.Builder{}
 	b.WriteByte(byte(p.Team))
-	b.WriteString(strconv.FormatUint(p.Y, 10))
-	b.WriteByte('#')
-	b.WriteString(strconv.FormatUint(p.X, 10))
+	switch p.Kind {
+	case PositionCorner:
+		b.WriteByte(byte(p.Neighbour))
+	case PositionEdge:
+		b.WriteByte(byte(p.Neighbour))
+		switch p.Neighbour {
+		case 'Y', 'B':
+			b.WriteString(strconv.FormatUint(p.X, 10))
+		default:
+			b.WriteString(strconv.FormatUint(p.Y, 10))
+		}
+	default:
+		b.WriteString(strconv.FormatUint(p.Y, 10))
+		b.WriteByte('#')
+		b.WriteString(strconv.FormatUint(p.X, 10))
+	}
 
 	return b.String()
 }
 
-func NewMapPosition(position []byte) Position {
+func (p Position) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + p.String() + "\""), nil
+}
+
+func (p *Position) UnmarshalJSON(b []byte) error {
+	s := strings.TrimFunc(string(b), func(r rune) bool {
+		return r == '"'
+	})
+	if s == "" {
+		*p = Position{}
+		return nil
+	}
+	pos, err := ParsePosition(s)
+	if err != nil {
+		return err
+	}
+	*p = pos
+	return nil
+}
+
+func isPositionTeam(c byte) bool {
+	_, ok := FactionByCode(Team(c))
+	return ok
+}
+
+func isCastle(c byte) bool {
+	faction, ok := FactionByCode(Team(c))
+	return ok && !faction.Monster
+}
+
+// ParsePosition parses the map position found between brackets on the
+// resume, as G3#4 for inner tiles, GY5 for edges and GY for corners.
+// Tiles on edges shared with Y or B castles vary on X, edges shared with
+// R or G castles vary on Y.
+func ParsePosition(position string) (Position, error) {
 	pos := Position{}
-	switch position[0] {
-	case 'Y', 'G', 'R', 'B':
-		pos.Team = Team(position[0])
-	default:
-		panic("invalid position string " + string(position))
+	if len(position) < 2 {
+		return pos, errors.New("invalid position string " + position)
+	}
+	if !isCastle(position[0]) {
+		return pos, errors.New("invalid position team " + position)
 	}
-	coord := bytes.Split(position[1:], []byte("#"))
-	if len(coord) == 1 {
-		switch coord[0][0] {
+	pos.Team = Team(position[0])
+
+	if isPositionTeam(position[1]) {
+		if !isCastle(position[1]) || position[1] == position[0] {
+			return Position{}, errors.New("invalid position neighbour " + position)
+		}
+		pos.Neighbour = Team(position[1])
+		if len(position) == 2 {
+			pos.Kind = PositionCorner
+			return pos, nil
+		}
+		pos.Kind = PositionEdge
+		n, err := strconv.ParseUint(position[2:], 10, 64)
+		if err != nil {
+			return Position{}, fmt.Errorf("position %s %w", position, err)
+		}
+		switch pos.Neighbour {
 		case 'Y', 'B':
-			pos.Y = 0
-			x, err := strconv.ParseUint(string(coord[0][1:]), 10, 64)
-			assert.NoError(err, "line %s", string(position))
-			pos.X = x
-		case 'R', 'G':
-			pos.X = 0
-			y, err := strconv.ParseUint(string(coord[0][1:]), 10, 64)
-			assert.NoError(err, "line %s", string(position))
-			pos.X = y
+			pos.X = n
+		default:
+			pos.Y = n
 		}
+		return pos, nil
+	}
 
-	} else {
-		y, err := strconv.ParseUint(string(coord[0]), 10, 64)
-		assert.NoError(err, "line %s", string(position))
-		x, err := strconv.ParseUint(string(coord[1]), 10, 64)
-		assert.NoError(err, "line %s", string(position))
-
-		pos.Y = y
-		pos.X = x
+	coord := strings.Split(position[1:], "#")
+	if len(coord) != 2 {
+		return Position{}, errors.New("invalid position coordinates " + position)
+	}
+	y, err := strconv.ParseUint(coord[0], 10, 64)
+	if err != nil {
+		return Position{}, fmt.Errorf("position %s %w", position, err)
 	}
+	x, err := strconv.ParseUint(coord[1], 10, 64)
+	if err != nil {
+		return Position{}, fmt.Errorf("position %s %w", position, err)
+	}
+	pos.Kind = PositionInner
+	pos.Y = y
+	pos.X = x
 
-	return pos
+	return pos, nil
 }
 
 type ResumeTeam struct {
@@ -205,14 +293,58 @@ func (rt ResumeTeam) String() string {
 	return b.String()
 }
 
+// BattleKind tells a territory battle between castles from a battle with
+// the creatures of a location. Logs without resume take the kind from the
+// turns, BattleUnknown is left when there are none.
+type BattleKind byte
+
+const (
+	BattleUnknown BattleKind = iota
+	BattleTerritory
+	BattleMonster
+)
+
+func (k BattleKind) String() string {
+	switch k {
+	case BattleTerritory:
+		return "territory"
+	case BattleMonster:
+		return "monster"
+	default:
+		return "unknown"
+	}
+}
+
+func (k BattleKind) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + k.String() + "\""), nil
+}
+
+func (k *BattleKind) UnmarshalJSON(b []byte) error {
+	s := strings.TrimFunc(string(b), func(r rune) bool {
+		return r == '"'
+	})
+	switch s {
+	case "unknown":
+		*k = BattleUnknown
+	case "territory":
+		*k = BattleTerritory
+	case "monster":
+		*k = BattleMonster
+	default:
+		return errors.New("invalid battle kind " + string(b))
+	}
+	return nil
+}
+
 type Resume struct {
+	Kind     BattleKind
 	Position Position
 	Teams    []ResumeTeam
 }
 
 func (r Resume) String() string {
 	b := strings.Builder{}
-	b.WriteString(r.Position.String())
+	b.WriteString(r.Kind.String() + " " + r.Position.String())
 	b.WriteByte('\n')
 	b.WriteString("teams:")
 
@@ -223,59 +355,267 @@ func (r Resume) String() string {
 	return b.String()
 }
 
+// Outcome of a strike. The zero value is OutcomeUnknown so a Strike that was
+// not filled by the parser is neither a hit nor a miss.
+type Outcome byte
+
+const (
+	OutcomeUnknown Outcome = iota
+	OutcomeHit
+	OutcomeMiss
+	OutcomeCounterHit
+	OutcomeCounterMiss
+	OutcomeDodge
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeHit:
+		return "hit"
+	case OutcomeMiss:
+		return "miss"
+	case OutcomeCounterHit:
+		return "counter-hit"
+	case OutcomeCounterMiss:
+		return "counter-miss"
+	case OutcomeDodge:
+		return "dodge"
+	default:
+		return "unknown"
+	}
+}
+
+func (o Outcome) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + o.String() + "\""), nil
+}
+
+func (o *Outcome) UnmarshalJSON(b []byte) error {
+	s := strings.TrimFunc(string(b), func(r rune) bool {
+		return r == '"'
+	})
+	switch s {
+	case "hit":
+		*o = OutcomeHit
+	case "miss":
+		*o = OutcomeMiss
+	case "counter-hit":
+		*o = OutcomeCounterHit
+	case "counter-miss":
+		*o = OutcomeCounterMiss
+	case "dodge":
+		*o = OutcomeDodge
+	default:
+		return errors.New("invalid outcome " + string(b))
+	}
+	return nil
+}
+
 type Strike struct {
-	Damage        int  `json:"damage"`
-	TargetDefense int  `json:"target_defense"`
-	Crit          bool `json:"crit"`
-	Weakness      bool `json:"weakness"`
+	Outcome       Outcome `json:"outcome"`
+	Damage        int     `json:"damage"`
+	TargetDefense int     `json:"target_defense"`
+	Crit          bool    `json:"crit"`
+	Weakness      bool    `json:"weakness"`
+	Counter       bool    `json:"counter"`
+	// 💦 modifier
+	Water bool `json:"water"`
+	// ➕ modifier
+	Cross bool `json:"cross"`
 }
 
 func (s Strike) String() string {
-	empty := Strike{}
-	if s == empty {
-		return "Miss"
+	if s.IsMiss() {
+		return s.Outcome.String()
 	}
 	return "Damage: " + strconv.FormatInt(int64(s.Damage), 10) + " Def" + strconv.FormatInt(int64(s.TargetDefense), 10)
 }
 
 func (s Strike) IsMiss() bool {
-	return s == Strike{}
+	switch s.Outcome {
+	case OutcomeMiss, OutcomeCounterMiss, OutcomeDodge:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Strike) IsHit() bool {
+	return s.Outcome == OutcomeHit || s.Outcome == OutcomeCounterHit
+}
+
+type EventKind byte
+
+const (
+	// line starting with a team flag
+	EventFlag EventKind = iota
+	EventArrowRetrieved
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventFlag:
+		return "flag"
+	case EventArrowRetrieved:
+		return "arrow_retrieved"
+	default:
+		return "unknown"
+	}
+}
+
+func (k EventKind) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + k.String() + "\""), nil
+}
+
+func (k *EventKind) UnmarshalJSON(b []byte) error {
+	s := strings.TrimFunc(string(b), func(r rune) bool {
+		return r == '"'
+	})
+	switch s {
+	case "flag":
+		*k = EventFlag
+	case "arrow_retrieved":
+		*k = EventArrowRetrieved
+	default:
+		return errors.New("invalid event kind " + string(b))
+	}
+	return nil
+}
+
+// TurnEvent is a line found after the strikes of a turn. ArrowRetrieved
+// events carry the User, Flag events carry the Team and the rest of the line.
+type TurnEvent struct {
+	Kind EventKind `json:"kind"`
+	User User      `json:"user"`
+	Team Team      `json:"team"`
+	Text string    `json:"text"`
+}
+
+func (e TurnEvent) String() string {
+	switch e.Kind {
+	case EventArrowRetrieved:
+		return e.User.LogString() + arrowRetrievedSuffix
+	default:
+		return e.Team.logFlag() + e.Text
+	}
 }
 
 type Turn struct {
 	Attacker User     `json:"atacker"`
 	Target   User     `json:"target"`
 	Strikes  []Strike `json:"strikes"`
+	// target HP at the start of the turn, as declared on the target line
+	TargetHPBefore int `json:"target_hp_before"`
+	// target HP after the attacker strikes
+	TargetHPAfter int `json:"target_hp_after"`
+	// number of strikes declared on the target line
+	DeclaredStrikes int `json:"declared_strikes"`
+	// the strikes found do not match DeclaredStrikes, see Options.Strict
+	StrikesMismatch bool `json:"strikes_mismatch,omitempty"`
+	TargetDied      bool `json:"target_died"`
+	// lines after the strikes
+	Events []TurnEvent `json:"events"`
 }
 
+// ownStrikes counts the strikes done by the attacker, leaving out the counter attacks
+func (t Turn) ownStrikes() int {
+	count := 0
+	for _, strike := range t.Strikes {
+		if !strike.Counter {
+			count++
+		}
+	}
+	return count
+}
+
+// CounterDamage is the damage the target did to the attacker
+func (t Turn) CounterDamage() int {
+	result := 0
+	for _, s := range t.Strikes {
+		if s.Counter {
+			result += s.Damage
+		}
+	}
+	return result
+}
+
+// Misses are the strikes of the attacker that missed, counter strikes are
+// left out as they belong to the target
 func (t Turn) Misses() int {
 	count := 0
 	for _, strike := range t.Strikes {
-		if strike.IsMiss() {
+		if !strike.Counter && strike.IsMiss() {
 			count++
 		}
 	}
 	return count
 }
 
+// Hits are the strikes of the attacker that hit, counter strikes are left
+// out as they belong to the target
 func (t Turn) Hits() int {
-	return len(t.Strikes) - t.Misses()
+	count := 0
+	for _, strike := range t.Strikes {
+		if !strike.Counter && strike.IsHit() {
+			count++
+		}
+	}
+	return count
+}
+
+// CounterMisses are the counter strikes of the target that missed
+func (t Turn) CounterMisses() int {
+	count := 0
+	for _, strike := range t.Strikes {
+		if strike.Counter && strike.IsMiss() {
+			count++
+		}
+	}
+	return count
 }
 
+// CounterHits are the counter strikes of the target that hit
+func (t Turn) CounterHits() int {
+	count := 0
+	for _, strike := range t.Strikes {
+		if strike.Counter && strike.IsHit() {
+			count++
+		}
+	}
+	return count
+}
+
+// Crits are the critical strikes of the attacker, counter strikes are left
+// out as they belong to the target
 func (t Turn) Crits() int {
 	count := 0
 	for _, strike := range t.Strikes {
-		if strike.Crit {
+		if !strike.Counter && strike.Crit {
+			count++
+		}
+	}
+	return count
+}
+
+// Weaknesses are the weakness strikes of the attacker, counter strikes are
+// left out as they belong to the target
+func (t Turn) Weaknesses() int {
+	count := 0
+	for _, strike := range t.Strikes {
+		if !strike.Counter && strike.Weakness {
 			count++
 		}
 	}
 	return count
 }
 
+// Damage is the damage the attacker did to the target, counter strikes are
+// left out as they belong to the target, see CounterDamage
 func (t Turn) Damage() int {
 	result := 0
 	for _, s := range t.Strikes {
-		result += s.Damage
+		if !s.Counter {
+			result += s.Damage
+		}
 	}
 	return result
 }
@@ -293,20 +633,40 @@ func (t Turn) String() string {
 	return b.String()
 }
 
+type Death struct {
+	User   User `json:"user"`
+	Killer User `json:"killer"`
+	// index of the turn on Battle.Turns
+	Turn int `json:"turn"`
+}
+
+// Diagnostic describes a card skipped while parsing on lenient mode
+type Diagnostic struct {
+	Card   int      `json:"card"`
+	Lines  []string `json:"lines"`
+	Reason string   `json:"reason"`
+}
+
 type Battle struct {
+	// text of the identifier card before the date
+	ID     string    `json:"id"`
 	Resume Resume    `json:"resume"`
 	Turns  []Turn    `json:"turns"`
 	Date   time.Time `json:"date"`
+	Deaths []Death   `json:"deaths"`
+	// creatures of a monster battle in order of appearance
+	Creatures []CreatureStats `json:"creatures,omitempty"`
+	Warnings  []Diagnostic    `json:"warnings,omitempty"`
 }
 
+// PlayerListWithDamage is the damage done by each player, the damage of the
+// counter strikes goes to the target that strikes back
 func (b Battle) PlayerListWithDamage() map[User]int {
 	result := make(map[User]int, 0)
 	for _, turn := range b.Turns {
-		r, ok := result[turn.Attacker]
-		if !ok {
-			result[turn.Attacker] = turn.Damage()
-		} else {
-			result[turn.Attacker] = r + turn.Damage()
+		result[turn.Attacker] += turn.Damage()
+		if counter := turn.CounterDamage(); counter > 0 {
+			result[turn.Target] += counter
 		}
 	}
 	return result
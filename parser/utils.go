@@ -46,8 +46,10 @@ func getNodeLines(n *html.Node) []string {
 		}
 	})
 
-	lines := strings.Split(b.String(), "\n")
-	lines = removeEmptyLines(lines)
+	return splitLines(b.String())
+}
 
-	return lines
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	return removeEmptyLines(lines)
 }
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ross96D/battle-log-parser/parser"
+	"github.com/ross96D/battle-log-parser/stats"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ErrUpstream Error = "upstream request failed"
+	ErrInternal Error = "internal error"
+)
+
+// ErrorBody is the JSON body of every error response
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// ParseErrorDetails are the details of an unparseable log
+type ParseErrorDetails struct {
+	Category string `json:"category"`
+	Card     int    `json:"card"`
+	Line     int    `json:"line"`
+	Text     string `json:"text"`
+	Reason   string `json:"reason"`
+}
+
+// badInput are the errors caused by the request
+var badInput = []error{
+	ErrNoUrlParam,
+	ErrInvalidUrlParam,
+	ErrEmptyBody,
+	ErrInvalidUpload,
+	ErrSchemeNotAllowed,
+	ErrHostNotAllowed,
+	ErrForbiddenAddress,
+	stats.ErrInvalidSortKey,
+}
+
+// unparseable are the errors of a log that is not a battle log
+var unparseable = []error{
+	parser.ErrNoBattleLog,
+	parser.ErrUnsupportedLayout,
+	parser.ErrUnknownTeam,
+}
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// errorResponse maps the error of a handler to the status and body of the
+// response
+func errorResponse(err error) (int, ErrorBody) {
+	var httpErr *echo.HTTPError
+	var maxBytesErr *http.MaxBytesError
+	var parseErr *parser.ParseError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.Code, ErrorBody{
+			Code:    "http_error",
+			Message: fmt.Sprint(httpErr.Message),
+		}
+
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, ErrorBody{
+			Code:    "upload_too_large",
+			Message: fmt.Sprintf("the upload is bigger than %d bytes", maxBytesErr.Limit),
+		}
+
+	case isAny(err, badInput):
+		return http.StatusBadRequest, ErrorBody{
+			Code:    "bad_request",
+			Message: err.Error(),
+		}
+
+	case isTimeout(err):
+		return http.StatusGatewayTimeout, ErrorBody{
+			Code:    "upstream_timeout",
+			Message: err.Error(),
+		}
+
+	case errors.Is(err, ErrUpstream), errors.Is(err, ErrTooManyRedirects),
+		errors.Is(err, ErrResponseTooLarge):
+		return http.StatusBadGateway, ErrorBody{
+			Code:    "upstream_error",
+			Message: err.Error(),
+		}
+
+	case errors.As(err, &parseErr):
+		return http.StatusUnprocessableEntity, ErrorBody{
+			Code:    "unparseable_log",
+			Message: err.Error(),
+			Details: ParseErrorDetails{
+				Category: parseErr.Category.String(),
+				Card:     parseErr.Card,
+				Line:     parseErr.Line,
+				Text:     parseErr.Text,
+				Reason:   parseErr.Err.Error(),
+			},
+		}
+
+	case isAny(err, unparseable), errors.As(err, &syntaxErr):
+		return http.StatusUnprocessableEntity, ErrorBody{
+			Code:    "unparseable_log",
+			Message: err.Error(),
+		}
+
+	default:
+		return http.StatusInternalServerError, ErrorBody{
+			Code:    "internal_error",
+			Message: ErrInternal.Error(),
+		}
+	}
+}
+
+// errorHandler writes the error as an ErrorBody
+func errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, body := errorResponse(err)
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(err).Int("status", status).Str("path", c.Path()).Msg("request failed")
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("writing error response")
+	}
+}
+
+// recoverPanic turns the panics of the handlers, as the asserts of the
+// parser, into an ErrInternal so the process keeps serving
+func recoverPanic(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().
+					Interface("panic", r).
+					Bytes("stack", debug.Stack()).
+					Msg("handler panic")
+				err = ErrInternal
+			}
+		}()
+		return next(c)
+	}
+}
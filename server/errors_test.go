@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ross96D/battle-log-parser/parser"
+	"github.com/ross96D/battle-log-parser/stats"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: ErrNoUrlParam, status: http.StatusBadRequest},
+		{err: fmt.Errorf("wrapped %w", stats.ErrInvalidSortKey), status: http.StatusBadRequest},
+		{err: fmt.Errorf("%w dial %w", ErrUpstream, ErrForbiddenAddress), status: http.StatusBadRequest},
+		{err: &http.MaxBytesError{Limit: 10}, status: http.StatusRequestEntityTooLarge},
+		{err: fmt.Errorf("parser.Parse %w", parser.ErrNoBattleLog), status: http.StatusUnprocessableEntity},
+		{err: &parser.ParseError{Err: errors.New("bad")}, status: http.StatusUnprocessableEntity},
+		{err: fmt.Errorf("%w connection refused", ErrUpstream), status: http.StatusBadGateway},
+		{err: fmt.Errorf("%w %w", ErrUpstream, timeoutError{}), status: http.StatusGatewayTimeout},
+		{err: errors.New("unexpected"), status: http.StatusInternalServerError},
+		{err: echo.ErrNotFound, status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if status, _ := errorResponse(test.err); status != test.status {
+			t.Errorf("%v expected %d got %d", test.err, test.status, status)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return false }
+
+func serve(s *echo.Echo, req *http.Request) (int, ErrorBody) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	body := ErrorBody{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec.Code, body
+}
+
+func TestErrorBody(t *testing.T) {
+	s := Server(Config{})
+
+	status, body := serve(s, httptest.NewRequest(http.MethodPost, "/parse", strings.NewReader("not a battle log")))
+	if status != http.StatusUnprocessableEntity || body.Code != "unparseable_log" {
+		t.Errorf("expected 422 unparseable_log got %d %+v", status, body)
+	}
+
+	status, body = serve(s, httptest.NewRequest(http.MethodGet, "/summary?sort=bad", nil))
+	if status != http.StatusBadRequest || body.Code != "bad_request" || body.Message == "" {
+		t.Errorf("expected 400 bad_request got %d %+v", status, body)
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	log := "📯Battle for [G3#4]\nTeams:\n🇲🇴Green Castle: ten total, 5 alive"
+	status, body := serve(Server(Config{}), httptest.NewRequest(http.MethodPost, "/parse", strings.NewReader(log)))
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 got %d %+v", status, body)
+	}
+	details, ok := body.Details.(map[string]any)
+	if !ok || details["category"] != "resume" {
+		t.Errorf("expected resume details got %+v", body.Details)
+	}
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	s := Server(Config{Fetch: FetchPolicy{AllowPrivateAddresses: true}})
+	status, body := serve(s, httptest.NewRequest(http.MethodGet, "/parse?url="+upstream.URL, nil))
+	if status != http.StatusBadGateway || body.Code != "upstream_error" {
+		t.Errorf("expected 502 upstream_error got %d %+v", status, body)
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	s := Server(Config{})
+	s.GET("/panic", func(c echo.Context) error {
+		panic("assert")
+	})
+
+	status, body := serve(s, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if status != http.StatusInternalServerError || body.Code != "internal_error" {
+		t.Errorf("expected 500 internal_error got %d %+v", status, body)
+	}
+}
+
+func TestMalformedMultipart(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "missing boundary", contentType: "multipart/form-data", body: "--x\r\n"},
+		{name: "garbage", contentType: "multipart/form-data; boundary=x", body: "garbage"},
+		{name: "missing file", contentType: "multipart/form-data; boundary=x", body: "--x\r\nContent-Disposition: form-data; name=\"other\"\r\n\r\nvalue\r\n--x--\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/parse", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			status, body := serve(Server(Config{}), req)
+			if status != http.StatusBadRequest || body.Code != "bad_request" {
+				t.Errorf("expected 400 bad_request got %d %+v", status, body)
+			}
+		})
+	}
+}
+
+func TestMultipartTooLarge(t *testing.T) {
+	body := "--x\r\nContent-Disposition: form-data; name=\"file\"; filename=\"log.html\"\r\n\r\n" +
+		strings.Repeat("a", 1000) + "\r\n--x--\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/parse", strings.NewReader(body))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	status, errBody := serve(Server(Config{MaxUploadSize: 100}), req)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 got %d %+v", status, errBody)
+	}
+}
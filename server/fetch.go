@@ -0,0 +1,222 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	ErrSchemeNotAllowed Error = "url scheme not allowed"
+	ErrHostNotAllowed   Error = "url host not allowed"
+	ErrForbiddenAddress Error = "address not allowed"
+	ErrTooManyRedirects Error = "too many redirects"
+	ErrResponseTooLarge Error = "response body too large"
+)
+
+const (
+	defaultFetchTimeout = 10 * time.Second
+	defaultMaxFetchSize = 10 << 20
+	defaultMaxRedirects = 3
+)
+
+var defaultAllowedSchemes = []string{"http", "https"}
+
+// deniedPrefixes are the address ranges that are not public internet. The
+// IPv6 ranges that embed an IPv4 address, NAT64, 6to4 and Teredo, are denied
+// whole as they may reach a private IPv4 address.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+func isDeniedAddress(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchPolicy limits the requests done for the url param
+type FetchPolicy struct {
+	// schemes allowed on the url, defaults to http and https
+	AllowedSchemes []string
+	// hosts allowed on the url, a host also allows its subdomains. Empty
+	// allows any host.
+	AllowedHosts []string
+	// allow the addresses that are not public, as loopback and private
+	// ones, only for testing
+	AllowPrivateAddresses bool
+	// timeout of the whole request, redirects included. Defaults to 10s
+	Timeout time.Duration
+	// max size in bytes of the response body, defaults to 10MB
+	MaxBodySize int64
+	// max redirects followed, defaults to 3. Negative disables redirects
+	MaxRedirects int
+}
+
+func (p FetchPolicy) withDefaults() FetchPolicy {
+	if len(p.AllowedSchemes) == 0 {
+		p.AllowedSchemes = defaultAllowedSchemes
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultFetchTimeout
+	}
+	if p.MaxBodySize <= 0 {
+		p.MaxBodySize = defaultMaxFetchSize
+	}
+	if p.MaxRedirects == 0 {
+		p.MaxRedirects = defaultMaxRedirects
+	}
+	return p
+}
+
+// checkURL validates the scheme and host of the url before any request
+func (p FetchPolicy) checkURL(u *url.URL) error {
+	if !slices.Contains(p.AllowedSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w %q", ErrSchemeNotAllowed, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w empty host", ErrHostNotAllowed)
+	}
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrHostNotAllowed, host)
+}
+
+// checkAddress rejects the addresses that are not public, see
+// deniedPrefixes. It runs after
+// the DNS resolution, on every connection, so a host can not resolve to an
+// allowed address on the check and to a private one on the request.
+func (p FetchPolicy) checkAddress(network string, address string, _ syscall.RawConn) error {
+	if p.AllowPrivateAddresses {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if isDeniedAddress(ip) {
+		return fmt.Errorf("%w %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// client returns the http client that applies the policy
+func (p FetchPolicy) client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: p.Timeout,
+		Control: p.checkAddress,
+	}
+	return &http.Client{
+		Timeout: p.Timeout,
+		Transport: &http.Transport{
+			// a proxy would make the address check useless
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: p.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return p.checkURL(req.URL)
+		},
+	}
+}
+
+// limitedBody fails with ErrResponseTooLarge instead of cutting the body
+type limitedBody struct {
+	r         io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedBody) Read(b []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// read one byte more than allowed to know if the body is too large
+	if int64(len(b)) > l.remaining+1 {
+		b = b[:l.remaining+1]
+	}
+	n, err := l.r.Read(b)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.r.Close()
+}
+
+// fetch gets the url applying the policy, the body is limited to
+// MaxBodySize
+func (p FetchPolicy) fetch(client *http.Client, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse() %s %w", rawURL, ErrInvalidUrlParam)
+	}
+	if err := p.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w http.Get %s %w", ErrUpstream, rawURL, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w %s responded %s", ErrUpstream, rawURL, resp.Status)
+	}
+	resp.Body = &limitedBody{r: resp.Body, remaining: p.MaxBodySize}
+	return resp, nil
+}
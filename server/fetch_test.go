@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	policy := FetchPolicy{AllowedHosts: []string{"example.com"}}.withDefaults()
+	tests := []struct {
+		url string
+		err error
+	}{
+		{url: "https://example.com/log", err: nil},
+		{url: "http://logs.example.com/log", err: nil},
+		{url: "https://notexample.com/log", err: ErrHostNotAllowed},
+		{url: "file:///etc/passwd", err: ErrSchemeNotAllowed},
+		{url: "gopher://example.com", err: ErrSchemeNotAllowed},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := policy.checkURL(u); !errors.Is(err, test.err) {
+			t.Errorf("%s expected %v got %v", test.url, test.err, err)
+		}
+	}
+}
+
+func TestFetchRejectsPrivateAddresses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("log"))
+	}))
+	defer upstream.Close()
+
+	policy := FetchPolicy{}.withDefaults()
+	_, err := policy.fetch(policy.client(), upstream.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress got %v", err)
+	}
+
+	policy.AllowPrivateAddresses = true
+	resp, err := policy.fetch(policy.client(), upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestFetchLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/redirect", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1000))
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	policy := FetchPolicy{AllowPrivateAddresses: true, MaxBodySize: 100}.withDefaults()
+	client := policy.client()
+
+	_, err := policy.fetch(client, upstream.URL+"/redirect")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("expected ErrTooManyRedirects got %v", err)
+	}
+
+	resp, err := policy.fetch(client, upstream.URL+"/large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge got %v", err)
+	}
+}
+
+func TestDeniedAddresses(t *testing.T) {
+	policy := FetchPolicy{}.withDefaults()
+	for _, prefix := range deniedPrefixes {
+		first := prefix.Addr()
+		last := first
+		for i := 0; i < 3 && prefix.Contains(last.Next()); i++ {
+			last = last.Next()
+		}
+		for _, ip := range []netip.Addr{first, last} {
+			address := net.JoinHostPort(ip.String(), "80")
+			if err := policy.checkAddress("tcp", address, nil); !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("%s on %s expected ErrForbiddenAddress got %v", ip, prefix, err)
+			}
+		}
+	}
+
+	denied := []string{"100.64.0.1", "::ffff:10.0.0.1", "64:ff9b::a00:1", "2002:a00:1::1", "[fe80::1%eth0]"}
+	for _, host := range denied {
+		address := net.JoinHostPort(strings.Trim(host, "[]"), "80")
+		if err := policy.checkAddress("tcp", address, nil); err == nil {
+			t.Errorf("%s expected error", host)
+		}
+	}
+
+	for _, host := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		if err := policy.checkAddress("tcp", net.JoinHostPort(host, "443"), nil); err != nil {
+			t.Errorf("%s unexpected error %v", host, err)
+		}
+	}
+}
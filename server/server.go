@@ -2,12 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/ross96D/battle-log-parser/parser"
+	"github.com/ross96D/battle-log-parser/stats"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,58 +24,212 @@ func (e Error) Error() string {
 const (
 	ErrNoUrlParam      Error = "required url param not set"
 	ErrInvalidUrlParam Error = "invalid url param"
+	ErrEmptyBody       Error = "empty request body"
+	ErrInvalidUpload   Error = "invalid upload"
 )
 
-func Server() *echo.Echo {
+// HeaderTruncated is set to true when the battle log was cut, the battle on
+// the response only holds the turns read up to the cut
+const HeaderTruncated = "X-Battle-Log-Truncated"
+
+// Config of the server, zero values take the defaults
+type Config struct {
+	// max size in bytes of the logs uploaded on POST, defaults to 10MB
+	MaxUploadSize int64
+	// policy of the requests done for the url param
+	Fetch FetchPolicy
+}
+
+const defaultMaxUploadSize = 10 << 20
+
+func (c Config) withDefaults() Config {
+	if c.MaxUploadSize <= 0 {
+		c.MaxUploadSize = defaultMaxUploadSize
+	}
+	c.Fetch = c.Fetch.withDefaults()
+	return c
+}
+
+type handler struct {
+	config Config
+	client *http.Client
+}
+
+func Server(config Config) *echo.Echo {
 	s := echo.New()
+	config = config.withDefaults()
+	h := handler{config: config, client: config.Fetch.client()}
 
-	s.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			if err := next(c); err != nil {
-				return echo.NewHTTPError(400, err)
-			}
-			return nil
-		}
-	})
+	s.HTTPErrorHandler = errorHandler
+	s.Use(recoverPanic)
 
-	s.GET("/parse", parse)
+	s.GET("/parse", h.parse)
+	s.POST("/parse", h.parse)
+	s.GET("/summary", h.summary)
+	s.POST("/summary", h.summary)
 
 	return s
 }
 
-func parse(c echo.Context) error {
-	urlStr := c.QueryParam("url")
-	if urlStr == "" {
-		return ErrNoUrlParam
+// parse responds with the battle and its summary. A POST always responds
+// with a list, as uploads like a Telegram export may hold several battles.
+func (h handler) parse(c echo.Context) error {
+	battles, truncated, err := h.battles(c)
+	if err != nil {
+		return err
+	}
+
+	type battleResponse struct {
+		parser.Battle
+		Summary   stats.BattleSummary `json:"summary"`
+		Truncated bool                `json:"truncated"`
+	}
+	responses := make([]battleResponse, 0, len(battles))
+	for _, b := range battles {
+		responses = append(responses, battleResponse{
+			Battle:    b,
+			Summary:   stats.Summarize(b),
+			Truncated: truncated,
+		})
 	}
 
-	_, err := url.Parse(urlStr)
+	var response any = responses
+	if c.Request().Method != http.MethodPost {
+		response = responses[0]
+	}
+
+	jenc := json.NewEncoder(c.Response())
+	err = jenc.Encode(response)
 	if err != nil {
-		return fmt.Errorf("url.Parse() %s %w", urlStr, ErrInvalidUrlParam)
+		return fmt.Errorf("jenc.Encode %w", err)
 	}
 
-	resp, err := http.Get(urlStr)
+	return nil
+}
+
+// summary responds with the summary of each battle. The sort param takes
+// one of the stats.SortKey values.
+func (h handler) summary(c echo.Context) error {
+	key, err := stats.ParseSortKey(c.QueryParam("sort"))
 	if err != nil {
-		return fmt.Errorf("http.Get %s %w", urlStr, err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	b, err := parser.Parse(resp.Body)
+	battles, truncated, err := h.battles(c)
 	if err != nil {
-		// TODO log io.Reader data
-		log.Error().Err(err).Msg("parsing function error")
-		return fmt.Errorf("parser.Parse %w", err)
+		return err
+	}
+
+	type summaryResponse struct {
+		stats.BattleSummary
+		Truncated bool `json:"truncated"`
+	}
+	summaries := make([]summaryResponse, 0, len(battles))
+	for _, b := range battles {
+		s := stats.Summarize(b)
+		s.SortBy(key)
+		summaries = append(summaries, summaryResponse{
+			BattleSummary: s,
+			Truncated:     truncated,
+		})
 	}
 
 	jenc := json.NewEncoder(c.Response())
-	err = jenc.Encode(b)
-	if err != nil {
+	if err := jenc.Encode(summaries); err != nil {
 		return fmt.Errorf("jenc.Encode %w", err)
 	}
-
 	return nil
 }
 
+// battles reads the battles of the request, from the url param on GET or
+// from the uploaded log on POST. A truncated log is not an error, it is
+// reported on the HeaderTruncated header and returned as truncated.
+func (h handler) battles(c echo.Context) (battles []parser.Battle, truncated bool, err error) {
+	if c.Request().Method != http.MethodPost {
+		var b parser.Battle
+		b, err = h.fetchBattle(c)
+		battles = []parser.Battle{b}
+	} else {
+		var r io.ReadCloser
+		r, err = h.upload(c)
+		if err != nil {
+			return nil, false, err
+		}
+		defer r.Close()
+		battles, err = parser.ParseAuto(r, parser.Options{})
+		if err != nil && !errors.Is(err, parser.ErrTruncatedLog) {
+			log.Error().Err(err).Msg("parsing function error")
+			err = fmt.Errorf("parser.ParseAuto %w", err)
+		}
+	}
+
+	if errors.Is(err, parser.ErrTruncatedLog) {
+		c.Response().Header().Set(HeaderTruncated, "true")
+		return battles, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return battles, false, nil
+}
+
+// upload returns the log of a POST, the "file" field of a multipart form or
+// the raw body as HTML or plain text. Both are limited to MaxUploadSize.
+func (h handler) upload(c echo.Context) (io.ReadCloser, error) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, h.config.MaxUploadSize)
+
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		// a malformed form is bad input, the size limit is still reported
+		// as *http.MaxBytesError
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("%w form file %w", ErrInvalidUpload, err)
+		}
+		f, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w form file %w", ErrInvalidUpload, err)
+		}
+		return f, nil
+	}
+
+	if req.ContentLength == 0 {
+		return nil, ErrEmptyBody
+	}
+	return req.Body, nil
+}
+
+// fetchBattle downloads and parses the battle log of the url param following
+// the fetch policy. A cut log returns the partial battle with ErrTruncatedLog
+func (h handler) fetchBattle(c echo.Context) (parser.Battle, error) {
+	urlStr := c.QueryParam("url")
+	if urlStr == "" {
+		return parser.Battle{}, ErrNoUrlParam
+	}
+
+	resp, err := h.config.Fetch.fetch(h.client, urlStr)
+	if err != nil {
+		return parser.Battle{}, err
+	}
+	defer resp.Body.Close()
+
+	opts := parser.Options{}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		opts.Reference = lastModified
+	}
+
+	b, err := parser.ParseWithOptions(resp.Body, opts)
+	if errors.Is(err, parser.ErrTruncatedLog) {
+		return b, err
+	}
+	if err != nil {
+		// TODO log io.Reader data
+		log.Error().Err(err).Msg("parsing function error")
+		return parser.Battle{}, fmt.Errorf("parser.Parse %w", err)
+	}
+	return b, nil
+}
+
 func ValidateUrlParam(uri string) error {
 	_, err := url.Parse(uri)
 
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ross96D/battle-log-parser/parser"
+	"github.com/ross96D/battle-log-parser/stats"
+)
+
+func readLog(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../parser/testdata/logs/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPostParse(t *testing.T) {
+	data := readLog(t, "territory.html")
+
+	form := bytes.Buffer{}
+	w := multipart.NewWriter(&form)
+	part, err := w.CreateFormFile("file", "territory.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+	}{
+		{name: "raw", body: data, contentType: "text/html"},
+		{name: "multipart", body: form.Bytes(), contentType: w.FormDataContentType()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			rec := httptest.NewRecorder()
+			Server(Config{}).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 got %d %s", rec.Code, rec.Body.String())
+			}
+			var battles []parser.Battle
+			if err := json.Unmarshal(rec.Body.Bytes(), &battles); err != nil {
+				t.Fatal(err)
+			}
+			if len(battles) != 1 || battles[0].ID != "Battle log #123" || len(battles[0].Turns) == 0 {
+				t.Errorf("unexpected battles %+v", battles)
+			}
+		})
+	}
+}
+
+func TestPostParseTooLarge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(readLog(t, "territory.html")))
+	rec := httptest.NewRecorder()
+	Server(Config{MaxUploadSize: 100}).ServeHTTP(rec, req)
+
+	if rec.Code == http.StatusOK {
+		t.Errorf("expected error got 200")
+	}
+}
+
+func TestPostParseTruncated(t *testing.T) {
+	data, err := os.ReadFile("../parser/testdata/broken/cut_in_turn.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/parse", bytes.NewReader(data))
+	rec := httptest.NewRecorder()
+	Server(Config{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(HeaderTruncated) != "true" {
+		t.Errorf("expected %s header", HeaderTruncated)
+	}
+	var response []struct {
+		Truncated bool `json:"truncated"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != 1 || !response[0].Truncated {
+		t.Errorf("expected truncated on the body")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	data := readLog(t, "territory.html")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer upstream.Close()
+	s := Server(Config{Fetch: FetchPolicy{AllowPrivateAddresses: true}})
+
+	tests := []struct {
+		name  string
+		req   *http.Request
+		first string
+	}{
+		{
+			name:  "get",
+			req:   httptest.NewRequest(http.MethodGet, "/summary?url="+upstream.URL, nil),
+			first: "[XYZ]🔥Carol",
+		},
+		{
+			name:  "post",
+			req:   httptest.NewRequest(http.MethodPost, "/summary", bytes.NewReader(data)),
+			first: "[XYZ]🔥Carol",
+		},
+		{
+			name:  "sort received",
+			req:   httptest.NewRequest(http.MethodPost, "/summary?sort=received", bytes.NewReader(data)),
+			first: "Bob",
+		},
+		{
+			name:  "sort name",
+			req:   httptest.NewRequest(http.MethodGet, "/summary?sort=name&url="+upstream.URL, nil),
+			first: "Bob",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, test.req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 got %d %s", rec.Code, rec.Body.String())
+			}
+			var summaries []stats.BattleSummary
+			if err := json.Unmarshal(rec.Body.Bytes(), &summaries); err != nil {
+				t.Fatal(err)
+			}
+			if len(summaries) != 1 || len(summaries[0].Players) == 0 {
+				t.Fatalf("unexpected summaries %+v", summaries)
+			}
+			if first := summaries[0].Players[0].Name; first != test.first {
+				t.Errorf("expected %s first got %s", test.first, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/ross96D/battle-log-parser/parser"
+)
+
+// BattleSummary is the whole summary of a battle with the players and
+// guilds sorted by damage done
+type BattleSummary struct {
+	ID        string                 `json:"id"`
+	Date      time.Time              `json:"date"`
+	Kind      parser.BattleKind      `json:"kind"`
+	Players   []PlayerSummary        `json:"players"`
+	Guilds    []PlayerSummary        `json:"guilds"`
+	Teams     []TeamSummary          `json:"teams"`
+	Creatures []parser.CreatureStats `json:"creatures,omitempty"`
+}
+
+func Summarize(b parser.Battle) BattleSummary {
+	players := Players(b)
+	guilds := Guilds(players)
+
+	result := BattleSummary{
+		ID:        b.ID,
+		Date:      b.Date,
+		Kind:      b.Resume.Kind,
+		Players:   make([]PlayerSummary, 0, len(players)),
+		Guilds:    make([]PlayerSummary, 0, len(guilds)),
+		Teams:     Teams(players),
+		Creatures: b.Creatures,
+	}
+	for _, k := range SortPlayers(players) {
+		result.Players = append(result.Players, players[k])
+	}
+	for _, k := range SortGuilds(guilds) {
+		result.Guilds = append(result.Guilds, guilds[k])
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package stats
+
+import "unicode/utf8"
+
+// FixedLenStr cuts or pads the string with spaces to width
+func FixedLenStr(str string, width uint) string {
+	strB := []byte(str)
+	nameLen := utf8.RuneCount(strB)
+	if nameLen > int(width) {
+		result := make([]byte, 0, width)
+		for i, r := range str {
+			if i == int(width) {
+				break
+			}
+			if r < 128 {
+				result = utf8.AppendRune(result, r)
+			} else {
+				_, size := utf8.DecodeRuneInString(string(r))
+				if len(result)+size > int(width) {
+					break
+				}
+				result = utf8.AppendRune(result, r)
+			}
+		}
+		return string(result)
+	} else {
+		for i := uint(0); i < (width - uint(nameLen)); i++ {
+			strB = append(strB, ' ')
+		}
+		return string(strB)
+	}
+}
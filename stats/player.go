@@ -0,0 +1,175 @@
+// Package stats summarizes a parsed battle by player, guild and team.
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ross96D/battle-log-parser/parser"
+)
+
+// Stats are the numbers added for a player, a guild or a team
+type Stats struct {
+	Damage     int `json:"damage"`
+	Received   int `json:"received"`
+	Misses     int `json:"misses"`
+	Hits       int `json:"hits"`
+	Crits      int `json:"crits"`
+	Weaknesses int `json:"weaknesses"`
+	Kills      int `json:"kills"`
+	Deaths     int `json:"deaths"`
+	// hits over hits and misses, kept up to date by Add
+	Accuracy float64 `json:"accuracy"`
+}
+
+func (s Stats) Add(other Stats) Stats {
+	result := Stats{
+		Damage:     s.Damage + other.Damage,
+		Received:   s.Received + other.Received,
+		Hits:       s.Hits + other.Hits,
+		Misses:     s.Misses + other.Misses,
+		Crits:      s.Crits + other.Crits,
+		Weaknesses: s.Weaknesses + other.Weaknesses,
+		Kills:      s.Kills + other.Kills,
+		Deaths:     s.Deaths + other.Deaths,
+	}
+	if result.Hits+result.Misses > 0 {
+		result.Accuracy = float64(result.Hits) / float64(result.Hits+result.Misses)
+	}
+	return result
+}
+
+type PlayerSummary struct {
+	Team  parser.Team `json:"team"`
+	Name  string      `json:"name"`
+	Guild string      `json:"guild"`
+	Stats
+}
+
+func (pr PlayerSummary) String() string {
+	b := strings.Builder{}
+	b.WriteString(pr.Team.String())
+	b.WriteString(fmt.Sprintf(
+		" %s\tdmg: %s\trecieved: %d\tHits/Total: %d/%d %.1f%%\tcrits: %d\tweakness: %d\tkills: %d\tdeaths: %d",
+		pr.NameWithFixedWidth(13), FixedLenStr(strconv.FormatInt(int64(pr.Damage), 10), 5), pr.Received, pr.Hits, pr.Hits+pr.Misses, 100*pr.Accuracy, pr.Crits, pr.Weaknesses, pr.Kills, pr.Deaths),
+	)
+	return b.String()
+}
+
+func (pr PlayerSummary) NameWithFixedWidth(width uint) string {
+	return FixedLenStr(pr.Name, width)
+}
+
+func (pr PlayerSummary) Add(other PlayerSummary) PlayerSummary {
+	return PlayerSummary{
+		Team:  pr.Team,
+		Name:  pr.Name,
+		Guild: pr.Guild,
+		Stats: pr.Stats.Add(other.Stats),
+	}
+}
+
+// Players summarizes the battle by player, the map is keyed by User.Key.
+// Each strike is credited to the player that does it, so counter strikes
+// are damage done by the target and received by the attacker.
+func Players(b parser.Battle) map[parser.User]PlayerSummary {
+	players := make(map[parser.User]*PlayerSummary, 0)
+	summary := func(u parser.User) *PlayerSummary {
+		key := u.Key()
+		p, ok := players[key]
+		if !ok {
+			p = &PlayerSummary{Team: u.Team, Name: u.Name, Guild: u.Guild}
+			players[key] = p
+		}
+		return p
+	}
+
+	for _, turn := range b.Turns {
+		attacker := summary(turn.Attacker)
+		if turn.Target.IsMiss() {
+			continue
+		}
+		target := summary(turn.Target)
+		for _, strike := range turn.Strikes {
+			striker, receiver := attacker, target
+			if strike.Counter {
+				striker, receiver = target, attacker
+			}
+			*striker = striker.Add(PlayerSummary{Stats: strikeStats(strike)})
+			*receiver = receiver.Add(PlayerSummary{Stats: Stats{Received: strike.Damage}})
+		}
+	}
+	for _, death := range b.Deaths {
+		summary(death.Killer).Kills++
+		summary(death.User).Deaths++
+	}
+
+	result := make(map[parser.User]PlayerSummary, len(players))
+	for k, p := range players {
+		result[k] = *p
+	}
+	return result
+}
+
+// strikeStats are the stats of the player that does the strike
+func strikeStats(strike parser.Strike) Stats {
+	s := Stats{Damage: strike.Damage}
+	if strike.IsHit() {
+		s.Hits = 1
+	}
+	if strike.IsMiss() {
+		s.Misses = 1
+	}
+	if strike.Crit {
+		s.Crits = 1
+	}
+	if strike.Weakness {
+		s.Weaknesses = 1
+	}
+	return s
+}
+
+// Guilds adds the summary of the players, as returned by Players, by guild
+// tag. Players without guild are left out.
+func Guilds(players map[parser.User]PlayerSummary) map[string]PlayerSummary {
+	result := make(map[string]PlayerSummary, 0)
+	for _, p := range players {
+		guild := p.Guild
+		if guild == "" {
+			continue
+		}
+		r, ok := result[guild]
+		if !ok {
+			r.Name = "[" + guild + "]"
+			r.Team = p.Team
+			r.Guild = guild
+		}
+		p.Name = r.Name
+		p.Team = r.Team
+		result[guild] = r.Add(p)
+	}
+	return result
+}
+
+// SortPlayers returns the keys of the map sorted by damage done
+func SortPlayers(m map[parser.User]PlayerSummary) []parser.User {
+	return sortByDamage(m)
+}
+
+// SortGuilds returns the keys of the map sorted by damage done
+func SortGuilds(m map[string]PlayerSummary) []string {
+	return sortByDamage(m)
+}
+
+func sortByDamage[K comparable](m map[K]PlayerSummary) []K {
+	result := make([]K, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	slices.SortFunc(result, func(a K, b K) int {
+		return SortDamage.comparePlayers(m[a], m[b])
+	})
+	return result
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const ErrInvalidSortKey Error = "invalid sort key"
+
+// SortKey is the field the summaries are sorted by, numbers are sorted from
+// the biggest and names alphabetically. Each key is the name of the field on
+// the JSON of Stats.
+type SortKey string
+
+const (
+	SortDamage     SortKey = "damage"
+	SortReceived   SortKey = "received"
+	SortHits       SortKey = "hits"
+	SortMisses     SortKey = "misses"
+	SortAccuracy   SortKey = "accuracy"
+	SortCrits      SortKey = "crits"
+	SortWeaknesses SortKey = "weaknesses"
+	SortKills      SortKey = "kills"
+	SortDeaths     SortKey = "deaths"
+	SortName       SortKey = "name"
+)
+
+// ParseSortKey validates the key, an empty key sorts by damage
+func ParseSortKey(s string) (SortKey, error) {
+	if s == "" {
+		return SortDamage, nil
+	}
+	key := SortKey(s)
+	switch key {
+	case SortDamage, SortReceived, SortHits, SortMisses, SortAccuracy,
+		SortCrits, SortWeaknesses, SortKills, SortDeaths, SortName:
+		return key, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidSortKey, s)
+}
+
+// compare orders the stats descending by the key
+func (key SortKey) compare(a Stats, b Stats) int {
+	switch key {
+	case SortReceived:
+		return b.Received - a.Received
+	case SortHits:
+		return b.Hits - a.Hits
+	case SortMisses:
+		return b.Misses - a.Misses
+	case SortAccuracy:
+		return cmp.Compare(b.Accuracy, a.Accuracy)
+	case SortCrits:
+		return b.Crits - a.Crits
+	case SortWeaknesses:
+		return b.Weaknesses - a.Weaknesses
+	case SortKills:
+		return b.Kills - a.Kills
+	case SortDeaths:
+		return b.Deaths - a.Deaths
+	default:
+		return b.Damage - a.Damage
+	}
+}
+
+func (key SortKey) comparePlayers(a PlayerSummary, b PlayerSummary) int {
+	if key == SortName {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Team, b.Team))
+	}
+	return cmp.Or(
+		key.compare(a.Stats, b.Stats),
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.Team, b.Team),
+	)
+}
+
+func (key SortKey) compareTeams(a TeamSummary, b TeamSummary) int {
+	if key == SortName {
+		return cmp.Compare(a.Team.Name(), b.Team.Name())
+	}
+	return cmp.Or(key.compare(a.Stats, b.Stats), cmp.Compare(a.Team, b.Team))
+}
+
+// SortBy sorts the players, guilds and teams of the summary by the key
+func (s BattleSummary) SortBy(key SortKey) {
+	slices.SortFunc(s.Players, key.comparePlayers)
+	slices.SortFunc(s.Guilds, key.comparePlayers)
+	slices.SortFunc(s.Teams, key.compareTeams)
+}
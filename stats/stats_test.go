@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ross96D/battle-log-parser/parser"
+)
+
+func parseLog(t *testing.T, name string) parser.Battle {
+	t.Helper()
+	f, err := os.Open("../parser/testdata/logs/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parser.ParseWithOptions(f, parser.Options{
+		Reference: time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(parseLog(t, "territory.html"))
+
+	if len(summary.Players) != 5 {
+		t.Fatalf("expected 5 players got %d", len(summary.Players))
+	}
+	first := summary.Players[0]
+	if first.Name != "[XYZ]🔥Carol" || first.Damage != 90 || first.Kills != 1 {
+		t.Errorf("unexpected first player %+v", first)
+	}
+	for i := 1; i < len(summary.Players); i++ {
+		if summary.Players[i-1].Damage < summary.Players[i].Damage {
+			t.Errorf("players not sorted by damage")
+		}
+	}
+
+	if len(summary.Guilds) != 2 || summary.Guilds[0].Guild != "XYZ" {
+		t.Errorf("unexpected guilds %+v", summary.Guilds)
+	}
+
+	damage := 0
+	players := 0
+	for _, team := range summary.Teams {
+		damage += team.Damage
+		players += team.Players
+	}
+	if players != len(summary.Players) {
+		t.Errorf("expected %d players on the teams got %d", len(summary.Players), players)
+	}
+	total := 0
+	for _, p := range summary.Players {
+		total += p.Damage
+	}
+	if damage != total {
+		t.Errorf("expected %d damage on the teams got %d", total, damage)
+	}
+}
+
+// TestCounterStrikes pins the numbers of the counter turns of territory.html,
+// Alice strikes back Bob for 4 and Carol misses back Dave
+func TestCounterStrikes(t *testing.T) {
+	players := make(map[string]PlayerSummary)
+	for _, p := range Players(parseLog(t, "territory.html")) {
+		players[p.Name] = p
+	}
+
+	tests := []struct {
+		name     string
+		expected Stats
+	}{
+		{name: "[ABC]Alice", expected: Stats{Damage: 41, Received: 12, Hits: 3, Misses: 1, Crits: 1}},
+		{name: "Bob", expected: Stats{Damage: 12, Received: 131, Hits: 1, Weaknesses: 1, Deaths: 1}},
+		{name: "[XYZ]🔥Carol", expected: Stats{Damage: 90, Hits: 2, Misses: 1, Crits: 1, Weaknesses: 1, Kills: 1}},
+		{name: "Dave", expected: Stats{Misses: 1}},
+	}
+	for _, test := range tests {
+		actual := players[test.name].Stats
+		actual.Accuracy = 0
+		if actual != test.expected {
+			t.Errorf("%s expected %+v got %+v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestFixedLenStr(t *testing.T) {
+	if s := FixedLenStr("Bob", 5); s != "Bob  " {
+		t.Errorf("expected padding got %q", s)
+	}
+	if s := FixedLenStr("Alexander", 4); s != "Alex" {
+		t.Errorf("expected cut got %q", s)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	if _, err := ParseSortKey("bad"); err == nil {
+		t.Errorf("expected invalid sort key")
+	}
+
+	summary := Summarize(parseLog(t, "territory.html"))
+	key, err := ParseSortKey("received")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary.SortBy(key)
+	if summary.Players[0].Name != "Bob" {
+		t.Errorf("expected Bob to receive the most damage got %s", summary.Players[0].Name)
+	}
+
+	summary.SortBy(SortName)
+	for i := 1; i < len(summary.Players); i++ {
+		if summary.Players[i-1].Name > summary.Players[i].Name {
+			t.Errorf("players not sorted by name")
+		}
+	}
+}
+
+func TestSortKeysMatchJSON(t *testing.T) {
+	data, err := json.Marshal(Stats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	keys := []SortKey{SortDamage, SortReceived, SortHits, SortMisses, SortAccuracy, SortCrits, SortWeaknesses, SortKills, SortDeaths}
+	for _, key := range keys {
+		if _, ok := fields[string(key)]; !ok {
+			t.Errorf("sort key %s is not a JSON field of Stats", key)
+		}
+	}
+}
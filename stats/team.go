@@ -0,0 +1,33 @@
+package stats
+
+import (
+	"slices"
+
+	"github.com/ross96D/battle-log-parser/parser"
+)
+
+type TeamSummary struct {
+	Team parser.Team `json:"team"`
+	// players of the team seen on the turns
+	Players int `json:"players"`
+	Stats
+}
+
+// Teams adds the summary of the players, as returned by Players, by team
+// sorted by damage done
+func Teams(players map[parser.User]PlayerSummary) []TeamSummary {
+	index := make(map[parser.Team]int)
+	result := make([]TeamSummary, 0)
+	for _, p := range players {
+		i, ok := index[p.Team]
+		if !ok {
+			i = len(result)
+			index[p.Team] = i
+			result = append(result, TeamSummary{Team: p.Team})
+		}
+		result[i].Players++
+		result[i].Stats = result[i].Stats.Add(p.Stats)
+	}
+	slices.SortFunc(result, SortDamage.compareTeams)
+	return result
+}